@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Draw the objects of a live cluster.",
+	Long: `Draw the objects of a live cluster, read through the current kubeconfig.
+Unlike for manifests, the ReplicaSets and Pods that actually run are drawn
+instead of children synthesized from the templates.`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kubeconfig, _ := cmd.Flags().GetString("kubeconfig")
+		kubeContext, _ := cmd.Flags().GetString("context")
+		namespace, _ := cmd.Flags().GetString("namespace")
+		allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+		strict, _ := cmd.Flags().GetBool("strict")
+		if allNamespaces && namespace != "" {
+			return fmt.Errorf("--namespace and --all-namespaces are mutually exclusive")
+		}
+		renderOption, err := newRenderOption(cmd)
+		if err != nil {
+			return err
+		}
+
+		// From here on errors concern the cluster, not the command line.
+		cmd.SilenceUsage = true
+
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = kubeconfig
+		clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+		if namespace == "" && !allNamespaces {
+			if namespace, _, err = clientConfig.Namespace(); err != nil {
+				return err
+			}
+		}
+		restConfig, err := clientConfig.ClientConfig()
+		if err != nil {
+			return err
+		}
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+
+		apiResourceList, listErrs := resource.NewAPIResourceListFromCluster(context.Background(), client, namespace)
+		if err := reportErrors(listErrs, strict); err != nil {
+			return err
+		}
+
+		return render(cmd, apiResourceList, renderOption)
+	},
+}
+
+func init() {
+	clusterCmd.Flags().String("kubeconfig", "", "Path to the kubeconfig file (default is $KUBECONFIG or ~/.kube/config).")
+	clusterCmd.Flags().String("context", "", "Kubeconfig context to use (default is the current context).")
+	clusterCmd.Flags().StringP("namespace", "n", "", "Namespace to draw (default is the namespace of the context).")
+	clusterCmd.Flags().BoolP("all-namespaces", "A", false, "Draw all namespaces, including cluster-wide RBAC.")
+	clusterCmd.Flags().Bool("strict", false, "Fail on any kind that cannot be listed instead of skipping it.")
+	addRenderFlags(clusterCmd.Flags())
+	rootCmd.AddCommand(clusterCmd)
+}
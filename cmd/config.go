@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gashirar/kuml/pkg/plantuml"
+	"github.com/gashirar/kuml/pkg/resource"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultConfigFile is looked up in the working directory, then in the home
+// directory, unless --config is given.
+const defaultConfigFile = ".kuml.yaml"
+
+type config struct {
+	Rules []plantuml.DeclarativeRule `json:"rules"`
+	// ClusterScopedKinds are custom resource kinds that have no namespace.
+	ClusterScopedKinds []string `json:"clusterScopedKinds"`
+}
+
+func loadConfig(path string) (config, error) {
+	c := config{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := plantuml.AddDeclarativeRules(c.Rules); err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, kind := range c.ClusterScopedKinds {
+		resource.RegisterClusterScopedKind(kind)
+	}
+	return c, nil
+}
+
+func findConfigFile() string {
+	candidates := []string{defaultConfigFile}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, defaultConfigFile))
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+	}
+	return ""
+}
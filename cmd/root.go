@@ -16,37 +16,72 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gashirar/kuml/pkg/plantuml"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"os"
 
 	"github.com/gashirar/kuml/pkg/resource"
 )
 
+var cfgFile string
+
 // rootCmd represents the base command when called without any subcommands
 
 var rootCmd = &cobra.Command{
-	Use:   "kuml [FILE | DIRECTORY]",
+	Use:   "kuml [FILE | DIRECTORY | -]...",
 	Short: "Kuml is a Manifest visualization tool.",
 	Long:  `Kuml is a misualization tool that outputs PlantUML from Kubernetes YAML Manifest.`,
 
-	Run: func(cmd *cobra.Command, args []string) {
-		yamlByteSlice := resource.ReadYaml(false, args...)
-		apiResourceList := resource.NewAPIResourceList(yamlByteSlice)
-		pUml := plantuml.NewPlantUML(apiResourceList)
-		pUml.Render()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, _ := cmd.Flags().GetString("namespace")
+		strict, _ := cmd.Flags().GetBool("strict")
+		renderOption, err := newRenderOption(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			if !isStdinPiped() {
+				return fmt.Errorf("requires at least one FILE or DIRECTORY, or manifests piped to stdin")
+			}
+			args = []string{resource.StdinPath}
+		}
+
+		// From here on errors concern the input, not the command line.
+		cmd.SilenceUsage = true
 
+		valueFiles, _ := cmd.Flags().GetStringSlice("values")
+		values, _ := cmd.Flags().GetStringArray("set")
+		noKustomize, _ := cmd.Flags().GetBool("no-kustomize")
+		readOptions := resource.ReadOptions{
+			Recursive:   true,
+			Chart:       resource.ChartOptions{ValueFiles: valueFiles, Values: values, Namespace: namespace},
+			NoKustomize: noKustomize,
+		}
+		if (len(valueFiles) > 0 || len(values) > 0) && !hasChart(args) {
+			fmt.Fprintln(os.Stderr, "Warning: --values and --set are ignored, no argument is a Helm chart directory")
+		}
+		yamlByteSlice, readErrs := resource.ReadYaml(readOptions, args...)
+		apiResourceList, parseErrs := resource.NewAPIResourceList(yamlByteSlice, namespace)
+		if err := reportErrors(append(readErrs, parseErrs...), strict); err != nil {
+			return err
+		}
+
+		return render(cmd, apiResourceList, renderOption)
 	},
 
-	Args: cobra.ExactArgs(1),
+	Args:          cobra.ArbitraryArgs,
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
@@ -54,9 +89,160 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.Flags().BoolP("show-link-label", "s", false, "Display the label of Link between Elements.")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file with additional link rules (default is ./.kuml.yaml, then $HOME/.kuml.yaml).")
+
+	rootCmd.Flags().StringP("namespace", "n", "default", "Namespace for objects that do not specify one.")
+	rootCmd.Flags().Bool("strict", false, "Fail on any document that cannot be parsed instead of skipping it.")
+	rootCmd.Flags().StringSlice("values", nil, "Values files for Helm chart directories, merged in order.")
+	rootCmd.Flags().StringArray("set", nil, "Values for Helm chart directories, e.g. image.tag=1.0 (can be repeated).")
+	rootCmd.Flags().Bool("no-kustomize", false, "Read kustomization directories file by file instead of building them.")
+	addRenderFlags(rootCmd.Flags())
 }
 
-func initConfig() {
+// addRenderFlags adds the flags shared by every command that draws a diagram.
+func addRenderFlags(flags *pflag.FlagSet) {
+	flags.BoolP("show-link-label", "s", false, "Display the label of Link between Elements.")
+	flags.Bool("show-role-rules", false, "Display a summary of the verbs and resources of Roles and ClusterRoles.")
+	flags.StringP("output", "o", "plantuml", "Output format. One of: plantuml, dot, mermaid, json, yaml.")
+	flags.String("group-by", plantuml.GroupByNamespace, "Group elements by one of: none, namespace, app, label=<key>, file.")
+	flags.String("style", plantuml.StyleRectangles, "Element style of the PlantUML output. One of: rectangles, icons.")
+	flags.String("icon-include", plantuml.DefaultIconInclude, "Sprite library included by --style icons, e.g. a local path for offline rendering.")
+	flags.Bool("hide-unknown", false, "Hide objects of kinds kuml has no schema for, e.g. custom resources.")
+	flags.String("view", plantuml.ViewAll, "Objects to show. One of: all, network (only Pods and the traffic NetworkPolicies allow).")
+	flags.StringSlice("rules", nil, "Comma-separated link rules to evaluate instead of all of them. See \"kuml rules list\".")
+	flags.StringSlice("skip-rules", nil, "Comma-separated link rules to skip. See \"kuml rules list\".")
+}
+
+func newRenderOption(cmd *cobra.Command) (plantuml.RenderOption, error) {
+	showLinkLabel, _ := cmd.Flags().GetBool("show-link-label")
+	groupBy, _ := cmd.Flags().GetString("group-by")
+	if err := plantuml.ValidateGroupBy(groupBy); err != nil {
+		return plantuml.RenderOption{}, err
+	}
+	style, _ := cmd.Flags().GetString("style")
+	if err := plantuml.ValidateStyle(style); err != nil {
+		return plantuml.RenderOption{}, err
+	}
+	iconInclude, _ := cmd.Flags().GetString("icon-include")
+	showRoleRules, _ := cmd.Flags().GetBool("show-role-rules")
+	hideUnknown, _ := cmd.Flags().GetBool("hide-unknown")
+	rules, _ := cmd.Flags().GetStringSlice("rules")
+	skipRules, _ := cmd.Flags().GetStringSlice("skip-rules")
+	if err := plantuml.ValidateRules(append(rules, skipRules...)); err != nil {
+		return plantuml.RenderOption{}, err
+	}
+	view, _ := cmd.Flags().GetString("view")
+	if err := plantuml.ValidateView(view); err != nil {
+		return plantuml.RenderOption{}, err
+	}
+	return plantuml.RenderOption{
+		ShowLinkLabel: showLinkLabel,
+		GroupBy:       groupBy,
+		Style:         style,
+		IconInclude:   iconInclude,
+		ShowRoleRules: showRoleRules,
+		View:          view,
+		HideUnknown:   hideUnknown,
+		Rules:         rules,
+		SkipRules:     skipRules,
+	}, nil
+}
+
+func render(cmd *cobra.Command, apiResourceList resource.APIResourceList, renderOption plantuml.RenderOption) error {
+	output, _ := cmd.Flags().GetString("output")
+
+	var renderer plantuml.Renderer
+	switch output {
+	case "plantuml":
+		pUml := plantuml.NewPlantUML(apiResourceList, renderOption)
+		renderer = &pUml
+	case "dot":
+		dot := plantuml.NewDot(apiResourceList, renderOption)
+		renderer = &dot
+	case "mermaid":
+		mermaid := plantuml.NewMermaid(apiResourceList, renderOption)
+		renderer = &mermaid
+	case "json", "yaml":
+		export := plantuml.NewExport(apiResourceList, renderOption, output)
+		renderer = &export
+	default:
+		return fmt.Errorf("unknown output format %q (must be one of: plantuml, dot, mermaid, json, yaml)", output)
+	}
+	return renderer.Render(os.Stdout)
+}
+
+// reportErrors prints errs to stderr. Documents that cannot be parsed and
+// kinds that cannot be listed are skipped with a warning unless strict is
+// set; unreadable paths always fail.
+func reportErrors(errs []error, strict bool) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	fatal := strict
+	invalidDocuments, unlistedKinds := 0, 0
+	for _, err := range errs {
+		var parseErr *resource.ParseError
+		var listErr *resource.ListError
+		switch {
+		case errors.As(err, &parseErr):
+			invalidDocuments++
+		case errors.As(err, &listErr):
+			unlistedKinds++
+		default:
+			fatal = true
+		}
+	}
+
+	level := "Warning:"
+	if fatal {
+		level = "Error:"
+	}
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, level, err)
+	}
+
+	if fatal {
+		return fmt.Errorf("%d problem(s) found in the input", len(errs))
+	}
+	if invalidDocuments > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d invalid document(s), use --strict to fail instead\n", invalidDocuments)
+	}
+	if unlistedKinds > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d kind(s) that could not be listed, use --strict to fail instead\n", unlistedKinds)
+	}
+	return nil
+}
+
+func hasChart(paths []string) bool {
+	for _, path := range paths {
+		if resource.IsChart(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isStdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice == 0
+}
 
+// initConfig loads the link rules declared in the config file. An explicitly
+// given file must exist, the default locations are optional.
+func initConfig() {
+	path := cfgFile
+	if path == "" {
+		path = findConfigFile()
+	}
+	if path == "" {
+		return
+	}
+	if _, err := loadConfig(path); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/plantuml"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Inspect the link rules.",
+}
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the link rules, including the ones declared in the config file.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tFROM\tTO")
+		for _, rule := range plantuml.LinkRules() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", rule.Name(), strings.Join(rule.SourceKinds(), ","), strings.Join(rule.TargetKinds(), ","))
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rulesCmd.AddCommand(rulesListCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
@@ -3,8 +3,12 @@ module github.com/gashirar/kuml
 go 1.14
 
 require (
-	github.com/spf13/cobra v0.0.7
-	k8s.io/api v0.18.1
-	k8s.io/apimachinery v0.18.1
+	github.com/spf13/cobra v1.0.0
+	github.com/spf13/pflag v1.0.5
+	helm.sh/helm/v3 v3.4.2
+	k8s.io/api v0.21.14
+	k8s.io/apimachinery v0.21.14
+	k8s.io/client-go v0.21.14
+	sigs.k8s.io/kustomize/api v0.8.8
 	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,242 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+	"reflect"
+	"strings"
+)
+
+// DeclarativeRule describes a link rule in the configuration file. Exactly
+// one of FieldPath and Selector is set:
+//
+//   - FieldPath is a JSONPath to the name(s) of the target objects, e.g.
+//     ".spec.secretRef.name" or ".spec.volumes[*].secret.secretName".
+//   - Selector is a JSONPath to a label selector, either a metav1.LabelSelector
+//     with matchLabels/matchExpressions or a plain map of labels, that is
+//     matched against the labels of the target objects.
+type DeclarativeRule struct {
+	Name      string          `json:"name"`
+	From      DeclarativeKind `json:"from"`
+	To        DeclarativeKind `json:"to"`
+	FieldPath string          `json:"fieldPath,omitempty"`
+	Selector  string          `json:"selector,omitempty"`
+	// Direction is one of DOWN (default), UP, LEFT and RIGHT.
+	Direction string `json:"direction,omitempty"`
+}
+
+type DeclarativeKind struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion,omitempty"`
+}
+
+func (k DeclarativeKind) matches(res resource.APIResource) bool {
+	gvk := res.GroupVersionKind()
+	return gvk.Kind == k.Kind && (k.APIVersion == "" || gvk.GroupVersion().String() == k.APIVersion)
+}
+
+// declaredKinds are the From kinds of the rules added by AddDeclarativeRules.
+// UnstructuredToReference leaves these kinds to their declared rules, so that
+// a reference is not linked twice.
+var declaredKinds []DeclarativeKind
+
+func hasDeclaredRule(res resource.APIResource) bool {
+	for _, k := range declaredKinds {
+		if k.matches(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// findDeclarativeTarget returns the object of kind k named name, or nil if
+// the list has none.
+func findDeclarativeTarget(list resource.APIResourceList, k DeclarativeKind, namespace string, name string) resource.APIResource {
+	for _, res := range list.Items {
+		if k.matches(res) && res.GetNamespace() == namespace && res.GetName() == name {
+			return res
+		}
+	}
+	return nil
+}
+
+// AddDeclarativeRules validates rules and registers them as link rules.
+func AddDeclarativeRules(rules []DeclarativeRule) error {
+	for i, rule := range rules {
+		fn, err := newDeclarativeLinkFunc(rule)
+		if err != nil {
+			if rule.Name != "" {
+				return fmt.Errorf("rule %q: %v", rule.Name, err)
+			}
+			return fmt.Errorf("rule %d: %v", i, err)
+		}
+		linkRule := NewLinkRule(rule.Name, []string{rule.From.Kind}, []string{rule.To.Kind}, fn)
+		if err := RegisterLinkRule(linkRule); err != nil {
+			return err
+		}
+		declaredKinds = append(declaredKinds, rule.From)
+	}
+	return nil
+}
+
+func newDeclarativeLinkFunc(rule DeclarativeRule) (func(resource.APIResourceList) LinkList, error) {
+	if rule.Name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+	if rule.From.Kind == "" || rule.To.Kind == "" {
+		return nil, fmt.Errorf("from.kind and to.kind are required")
+	}
+	if (rule.FieldPath == "") == (rule.Selector == "") {
+		return nil, fmt.Errorf("exactly one of fieldPath and selector must be set")
+	}
+	connector, err := directionToConnector(rule.Direction)
+	if err != nil {
+		return nil, err
+	}
+
+	path := rule.FieldPath
+	if path == "" {
+		path = rule.Selector
+	}
+	// The JSONPath is parsed up front so that a typo is reported when the
+	// configuration is loaded.
+	j, err := parseJSONPath(rule.Name, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(apiList resource.APIResourceList) LinkList {
+		linkList := LinkList{}
+
+		for _, res := range apiList.Items {
+			if !rule.From.matches(res) {
+				continue
+			}
+			values, err := findJSONPathValues(j, res)
+			if err != nil {
+				continue
+			}
+			from := resourceId(res)
+
+			if rule.FieldPath != "" {
+				namespace := res.GetNamespace()
+				if resource.IsClusterScoped(rule.To.Kind) {
+					namespace = ""
+				}
+				for _, value := range values {
+					if name, ok := value.(string); ok && name != "" {
+						to := createUniqueId(namespace, rule.To.Kind, name)
+						if target := findDeclarativeTarget(apiList, rule.To, namespace, name); target != nil {
+							to = resourceId(target)
+						}
+						linkList.Items = append(linkList.Items, NewLink(from, to, connector, path, path+": "+name))
+					}
+				}
+				continue
+			}
+
+			for _, value := range values {
+				selector, ok := toLabelSelector(value)
+				if !ok {
+					continue
+				}
+				for _, targetRes := range apiList.Items {
+					if rule.To.matches(targetRes) && targetRes.GetNamespace() == res.GetNamespace() {
+						if labelSelectorMatches(selector, targetRes.GetLabels()) {
+							to := resourceId(targetRes)
+							linkList.Items = append(linkList.Items, NewLink(from, to, connector, path, labelSelectorToString(selector)))
+						}
+					}
+				}
+			}
+		}
+		return linkList
+	}, nil
+}
+
+func directionToConnector(direction string) (string, error) {
+	switch strings.ToUpper(direction) {
+	case "", "DOWN":
+		return "-DOWN->", nil
+	case "UP":
+		return "-UP->", nil
+	case "LEFT":
+		return "-LEFT->", nil
+	case "RIGHT":
+		return "-RIGHT->", nil
+	}
+	return "", fmt.Errorf("unknown direction %q (must be one of: DOWN, UP, LEFT, RIGHT)", direction)
+}
+
+// parseJSONPath accepts both kubectl style templates ("{.spec.x}") and bare
+// paths (".spec.x").
+func parseJSONPath(name string, path string) (*jsonpath.JSONPath, error) {
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	j := jsonpath.New(name).AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func findJSONPathValues(j *jsonpath.JSONPath, res resource.APIResource) ([]interface{}, error) {
+	var object map[string]interface{}
+	var err error
+	if u, ok := res.(*unstructured.Unstructured); ok {
+		object = u.Object
+	} else if object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(res); err != nil {
+		return nil, err
+	}
+
+	results, err := j.FindResults(object)
+	if err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for _, result := range results {
+		for _, value := range result {
+			if value.Kind() == reflect.Interface && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.IsValid() && value.CanInterface() {
+				values = append(values, value.Interface())
+			}
+		}
+	}
+	return values, nil
+}
+
+// toLabelSelector converts a label selector found in an object, either a
+// metav1.LabelSelector or a plain map of labels as used by Services.
+func toLabelSelector(value interface{}) (*metav1.LabelSelector, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil, false
+	}
+
+	_, hasMatchLabels := m["matchLabels"]
+	_, hasMatchExpressions := m["matchExpressions"]
+	if hasMatchLabels || hasMatchExpressions {
+		selector := &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, selector); err != nil {
+			return nil, false
+		}
+		return selector, true
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{}}
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		selector.MatchLabels[k] = s
+	}
+	return selector, true
+}
@@ -0,0 +1,149 @@
+package plantuml
+
+import (
+	"github.com/gashirar/kuml/pkg/resource"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const declarativeInput = `apiVersion: example.com/v1
+kind: MyApp
+metadata: {name: app, namespace: default}
+spec:
+  credentials:
+  - secretName: s1
+  - secretName: s2
+  selector:
+    matchLabels: {app: web}
+  policy: {name: strict}
+---
+apiVersion: v1
+kind: Secret
+metadata: {name: s1, namespace: default}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: web, namespace: default, labels: {app: web}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: db, namespace: default, labels: {app: db}}
+---
+apiVersion: example.com/v1
+kind: ClusterWidgetPolicy
+metadata: {name: strict}
+`
+
+func decodeTestResources(t *testing.T, input string) resource.APIResourceList {
+	t.Helper()
+	documents, err := resource.DecodeDocuments("test.yaml", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, errs := resource.NewAPIResourceList(documents, "default")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	return list
+}
+
+func TestDeclarativeRules(t *testing.T) {
+	resource.RegisterClusterScopedKind("ClusterWidgetPolicy")
+	list := decodeTestResources(t, declarativeInput)
+
+	tests := []struct {
+		name string
+		rule DeclarativeRule
+		want []string
+	}{
+		{
+			name: "fieldPath",
+			rule: DeclarativeRule{Name: "MyAppToSecret", From: DeclarativeKind{Kind: "MyApp", APIVersion: "example.com/v1"}, To: DeclarativeKind{Kind: "Secret"},
+				FieldPath: ".spec.credentials[*].secretName"},
+			// s2 is missing from the input and becomes a placeholder.
+			want: []string{
+				"default_MyApp_2eexample_2ecom_app -> default_Secret_s1",
+				"default_MyApp_2eexample_2ecom_app -> default_Secret_s2",
+			},
+		},
+		{
+			name: "fieldPath to a cluster-scoped custom resource",
+			rule: DeclarativeRule{Name: "MyAppToPolicy", From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "ClusterWidgetPolicy"},
+				FieldPath: ".spec.policy.name"},
+			want: []string{"default_MyApp_2eexample_2ecom_app -> cluster_ClusterWidgetPolicy_2eexample_2ecom_strict"},
+		},
+		{
+			name: "selector",
+			rule: DeclarativeRule{Name: "MyAppToPod", From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "Pod"},
+				Selector: ".spec.selector"},
+			want: []string{"default_MyApp_2eexample_2ecom_app -> default_Pod_web"},
+		},
+		{
+			name: "other apiVersion",
+			rule: DeclarativeRule{Name: "MyAppToSecret", From: DeclarativeKind{Kind: "MyApp", APIVersion: "example.com/v2"}, To: DeclarativeKind{Kind: "Secret"},
+				FieldPath: ".spec.credentials[*].secretName"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := newDeclarativeLinkFunc(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, link := range fn(list).Items {
+				got = append(got, link.From+" -> "+link.To)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got links %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclarativeRulesReplaceHeuristic(t *testing.T) {
+	defer func(rules []LinkRule, kinds []DeclarativeKind) {
+		linkRules = rules
+		declaredKinds = kinds
+	}(linkRules, declaredKinds)
+
+	list := decodeTestResources(t, declarativeInput)
+	err := AddDeclarativeRules([]DeclarativeRule{
+		{Name: "MyAppToSecret", From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "Secret"}, FieldPath: ".spec.credentials[*].secretName"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, link := range NewLinkList(list).Items {
+		if link.To == "default_Secret_s1" {
+			got = append(got, link.Rule+" "+link.FieldPath)
+		}
+	}
+	want := []string{"MyAppToSecret .spec.credentials[*].secretName"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got links to Secret s1 %v, want %v", got, want)
+	}
+}
+
+func TestAddDeclarativeRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule DeclarativeRule
+	}{
+		{name: "missing name", rule: DeclarativeRule{From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "Secret"}, FieldPath: ".spec.x"}},
+		{name: "missing kind", rule: DeclarativeRule{Name: "r", From: DeclarativeKind{Kind: "MyApp"}, FieldPath: ".spec.x"}},
+		{name: "fieldPath and selector", rule: DeclarativeRule{Name: "r", From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "Pod"}, FieldPath: ".spec.x", Selector: ".spec.y"}},
+		{name: "unknown direction", rule: DeclarativeRule{Name: "r", From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "Pod"}, FieldPath: ".spec.x", Direction: "SIDEWAYS"}},
+		{name: "invalid JSONPath", rule: DeclarativeRule{Name: "r", From: DeclarativeKind{Kind: "MyApp"}, To: DeclarativeKind{Kind: "Pod"}, FieldPath: ".spec[x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newDeclarativeLinkFunc(tt.rule); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
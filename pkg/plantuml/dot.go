@@ -0,0 +1,135 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	"io"
+	"sort"
+	"strings"
+)
+
+type Dot struct {
+	elementList  ElementList
+	linkList     LinkList
+	renderOption RenderOption
+}
+
+type dotNodeStyle struct {
+	shape     string
+	fillColor string
+}
+
+var dotNodeStyles = map[string]dotNodeStyle{
+	"CronJob":                 {shape: "box", fillColor: "#B3D9FF"},
+	"DaemonSet":               {shape: "box", fillColor: "#B3D9FF"},
+	"Deployment":              {shape: "box", fillColor: "#B3D9FF"},
+	"Job":                     {shape: "box", fillColor: "#B3D9FF"},
+	"ReplicaSet":              {shape: "box", fillColor: "#B3D9FF"},
+	"StatefulSet":             {shape: "box", fillColor: "#B3D9FF"},
+	"Pod":                     {shape: "box3d", fillColor: "#CCE5CC"},
+	"Service":                 {shape: "ellipse", fillColor: "#FFE0B3"},
+	"Ingress":                 {shape: "ellipse", fillColor: "#FFE0B3"},
+	"IngressClass":            {shape: "ellipse", fillColor: "#FFF2CC"},
+	"ConfigMap":               {shape: "note", fillColor: "#FFFFCC"},
+	"Secret":                  {shape: "note", fillColor: "#FFCCCC"},
+	"HorizontalPodAutoscaler": {shape: "hexagon", fillColor: "#E0CCFF"},
+	"PodDisruptionBudget":     {shape: "hexagon", fillColor: "#E0CCFF"},
+	"PersistentVolumeClaim":   {shape: "cylinder", fillColor: "#FFE6CC"},
+	"PersistentVolume":        {shape: "cylinder", fillColor: "#FFD1A3"},
+	"StorageClass":            {shape: "folder", fillColor: "#FFD1A3"},
+	"ServiceAccount":          {shape: "octagon", fillColor: "#D9D9D9"},
+	"Role":                    {shape: "component", fillColor: "#D9D9D9"},
+	"ClusterRole":             {shape: "component", fillColor: "#D9D9D9"},
+	"RoleBinding":             {shape: "cds", fillColor: "#D9D9D9"},
+	"ClusterRoleBinding":      {shape: "cds", fillColor: "#D9D9D9"},
+	"NetworkPolicy":           {shape: "hexagon", fillColor: "#CCE0FF"},
+	"Namespace":               {shape: "tab", fillColor: "#EEEEEE"},
+}
+
+var defaultDotNodeStyle = dotNodeStyle{shape: "box", fillColor: "#EEEEEE"}
+
+func NewDot(resource resource.APIResourceList, renderOption RenderOption) Dot {
+	elementList, linkList := newGraphModel(resource, renderOption)
+
+	return Dot{elementList: elementList, linkList: linkList, renderOption: renderOption}
+}
+
+func (d *Dot) Render(w io.Writer) error {
+	fmt.Fprintln(w, "digraph kuml {")
+	fmt.Fprintln(w, "  node [style=filled];")
+
+	e := d.elementList.Items
+	sort.Slice(e, func(i, j int) bool { return e[i].UniqueId < e[j].UniqueId })
+
+	for _, group := range groupElements(e, d.renderOption.GroupBy) {
+		// Ungrouped objects, e.g. cluster-scoped ones, are drawn outside of
+		// every cluster.
+		indent := "  "
+		if group.Key != "" {
+			fmt.Fprintf(w, "  subgraph %s {\n", dotQuote("cluster_"+group.Key))
+			fmt.Fprintf(w, "    label=%s;\n", dotQuote(group.Title))
+			indent = "    "
+		}
+		for _, elem := range group.Elements {
+			style, ok := dotNodeStyles[elem.Kind]
+			if !ok {
+				style = defaultDotNodeStyle
+			}
+			extra := ""
+			if elem.Source.Synthesized {
+				extra = ", style=\"filled,dashed\""
+			}
+			fmt.Fprintf(w, "%s%s [label=%s, shape=%s, fillcolor=%s%s];\n",
+				indent, dotQuote(elem.UniqueId), dotQuote(elem.description(d.renderOption)), style.shape, dotQuote(style.fillColor), extra)
+		}
+		if group.Key != "" {
+			fmt.Fprintln(w, "  }")
+		}
+	}
+
+	known := map[string]bool{}
+	for _, elem := range e {
+		known[elem.UniqueId] = true
+	}
+	for _, link := range d.linkList.Items {
+		// Either end of a link may be no parsed object: a placeholder such as
+		// "(No Target Pod)" or an ipBlock, an RBAC subject, or an object that
+		// is referenced but missing from the input. Those get a dashed node.
+		for _, id := range []string{link.From, link.To} {
+			if !known[id] {
+				known[id] = true
+				fmt.Fprintf(w, "  %s [shape=ellipse, style=dashed];\n", dotQuote(id))
+			}
+		}
+	}
+
+	for _, link := range d.linkList.Items {
+		attrs := []string{}
+		if d.renderOption.ShowLinkLabel && link.Label != "" {
+			attrs = append(attrs, "label="+dotQuote(link.Label))
+		}
+		if link.Connector == "-LEFT->" || link.Connector == "-RIGHT->" {
+			attrs = append(attrs, "constraint=false")
+		}
+		if link.Connector == trafficConnector {
+			attrs = append(attrs, "style=dashed", "color=blue")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(w, "  %s -> %s [%s];\n", dotQuote(link.From), dotQuote(link.To), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(w, "  %s -> %s;\n", dotQuote(link.From), dotQuote(link.To))
+		}
+	}
+
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// dotQuote returns s as a DOT double-quoted string. Labels already use the
+// two-character "\n" sequence for line breaks, which DOT understands as well.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return "\"" + s + "\""
+}
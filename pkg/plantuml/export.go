@@ -0,0 +1,106 @@
+package plantuml
+
+import (
+	"encoding/json"
+	"github.com/gashirar/kuml/pkg/resource"
+	"io"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// GraphSchemaVersion is bumped whenever a field of Graph, Node or Edge is
+// renamed or removed. Adding fields does not change the version.
+const GraphSchemaVersion = "v1"
+
+type Graph struct {
+	Version string `json:"version"`
+	Nodes   []Node `json:"nodes"`
+	Edges   []Edge `json:"edges"`
+}
+
+type Node struct {
+	Id         string            `json:"id"`
+	Kind       string            `json:"kind"`
+	APIVersion string            `json:"apiVersion"`
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Source     NodeSource        `json:"source"`
+	Details    []string          `json:"details,omitempty"`
+}
+
+type NodeSource struct {
+	File          string `json:"file"`
+	DocumentIndex int    `json:"documentIndex"`
+	Synthesized   bool   `json:"synthesized,omitempty"`
+	Chart         string `json:"chart,omitempty"`
+}
+
+type Edge struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Rule      string `json:"rule"`
+	FieldPath string `json:"fieldPath"`
+	Label     string `json:"label,omitempty"`
+}
+
+type Export struct {
+	elementList ElementList
+	linkList    LinkList
+	format      string
+}
+
+func NewExport(resource resource.APIResourceList, renderOption RenderOption, format string) Export {
+	elementList, linkList := newGraphModel(resource, renderOption)
+
+	return Export{elementList: elementList, linkList: linkList, format: format}
+}
+
+func NewGraph(elementList ElementList, linkList LinkList) Graph {
+	graph := Graph{Version: GraphSchemaVersion, Nodes: []Node{}, Edges: []Edge{}}
+
+	e := elementList.Items
+	sort.Slice(e, func(i, j int) bool { return e[i].UniqueId < e[j].UniqueId })
+	for _, elem := range e {
+		graph.Nodes = append(graph.Nodes, Node{
+			Id:         elem.UniqueId,
+			Kind:       elem.Kind,
+			APIVersion: elem.APIVersion,
+			Namespace:  elem.Namespace,
+			Name:       elem.Name,
+			Labels:     elem.Labels,
+			Source:     NodeSource{File: elem.Source.File, DocumentIndex: elem.Source.Index, Synthesized: elem.Source.Synthesized, Chart: elem.Source.Chart},
+			Details:    elem.Details,
+		})
+	}
+
+	for _, link := range linkList.Items {
+		graph.Edges = append(graph.Edges, Edge{
+			From:      link.From,
+			To:        link.To,
+			Rule:      link.Rule,
+			FieldPath: link.FieldPath,
+			Label:     strings.ReplaceAll(link.Label, "\\n", "\n"),
+		})
+	}
+	return graph
+}
+
+func (x *Export) Render(w io.Writer) error {
+	graph := NewGraph(x.elementList, x.linkList)
+
+	var out []byte
+	var err error
+	if x.format == "yaml" {
+		out, err = yaml.Marshal(graph)
+	} else {
+		out, err = json.MarshalIndent(graph, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,85 @@
+package plantuml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	GroupByNone      = "none"
+	GroupByNamespace = "namespace"
+	GroupByApp       = "app"
+	GroupByFile      = "file"
+	GroupByLabel     = "label="
+)
+
+// appLabelKeys are tried in order by GroupByApp.
+var appLabelKeys = []string{
+	"app.kubernetes.io/part-of",
+	"app.kubernetes.io/name",
+	"app",
+}
+
+type elementGroup struct {
+	Key      string
+	Title    string
+	Elements []Element
+}
+
+func ValidateGroupBy(groupBy string) error {
+	switch {
+	case groupBy == "", groupBy == GroupByNone, groupBy == GroupByNamespace, groupBy == GroupByApp, groupBy == GroupByFile:
+		return nil
+	case strings.HasPrefix(groupBy, GroupByLabel) && len(groupBy) > len(GroupByLabel):
+		return nil
+	}
+	return fmt.Errorf("unknown group-by %q (must be one of: none, namespace, app, label=<key>, file)", groupBy)
+}
+
+// groupElements splits elements into groups sorted by key. Elements that
+// do not belong to any group, e.g. cluster-scoped objects when grouping by
+// namespace, end up in the group with the empty key, which comes first.
+func groupElements(elements []Element, groupBy string) []elementGroup {
+	var groups []elementGroup
+	index := map[string]int{}
+	for _, elem := range elements {
+		key, title := elementGroupKey(elem, groupBy)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, elementGroup{Key: key, Title: title})
+		}
+		groups[i].Elements = append(groups[i].Elements, elem)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+func elementGroupKey(elem Element, groupBy string) (string, string) {
+	switch {
+	case groupBy == GroupByNone:
+		return "", ""
+	case groupBy == GroupByApp:
+		for _, key := range appLabelKeys {
+			if value := elem.Labels[key]; value != "" {
+				return value, "app: " + value
+			}
+		}
+		return "", ""
+	case groupBy == GroupByFile:
+		return elem.Source.File, "file: " + elem.Source.File
+	case strings.HasPrefix(groupBy, GroupByLabel):
+		key := strings.TrimPrefix(groupBy, GroupByLabel)
+		if value := elem.Labels[key]; value != "" {
+			return value, key + ": " + value
+		}
+		return "", ""
+	default:
+		if elem.Namespace == "" {
+			return "", ""
+		}
+		return elem.Namespace, "namespace: " + elem.Namespace
+	}
+}
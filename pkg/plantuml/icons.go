@@ -0,0 +1,53 @@
+package plantuml
+
+import "fmt"
+
+const (
+	StyleRectangles = "rectangles"
+	StyleIcons      = "icons"
+)
+
+// DefaultIconInclude is the kubernetes sprite library shipped with the
+// PlantUML standard library. A local copy of k8s-sprites-unlabeled-25pct.iuml
+// from https://github.com/dcasati/kubernetes-PlantUML can be used instead
+// when rendering offline.
+const DefaultIconInclude = "<kubernetes/k8s-sprites-unlabeled-25pct>"
+
+var kindSprites = map[string]string{
+	"ClusterRole":              "c_role",
+	"ClusterRoleBinding":       "crb",
+	"ConfigMap":                "cm",
+	"CronJob":                  "cronjob",
+	"CustomResourceDefinition": "crd",
+	"DaemonSet":                "ds",
+	"Deployment":               "deploy",
+	"Endpoints":                "ep",
+	"HorizontalPodAutoscaler":  "hpa",
+	"Ingress":                  "ing",
+	"Job":                      "job",
+	"LimitRange":               "limits",
+	"Namespace":                "ns",
+	"NetworkPolicy":            "netpol",
+	"Node":                     "node",
+	"PersistentVolume":         "pv",
+	"PersistentVolumeClaim":    "pvc",
+	"Pod":                      "pod",
+	"PodSecurityPolicy":        "psp",
+	"ReplicaSet":               "rs",
+	"ResourceQuota":            "quota",
+	"Role":                     "role",
+	"RoleBinding":              "rb",
+	"Secret":                   "secret",
+	"Service":                  "svc",
+	"ServiceAccount":           "sa",
+	"StatefulSet":              "sts",
+	"StorageClass":             "sc",
+}
+
+func ValidateStyle(style string) error {
+	switch style {
+	case "", StyleRectangles, StyleIcons:
+		return nil
+	}
+	return fmt.Errorf("unknown style %q (must be one of: rectangles, icons)", style)
+}
@@ -0,0 +1,109 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	"io"
+	"sort"
+	"strings"
+)
+
+type Mermaid struct {
+	elementList  ElementList
+	linkList     LinkList
+	renderOption RenderOption
+}
+
+// Mermaid cannot pin the direction of a single edge, so the vertical
+// connectors keep the solid top-down arrow and the lateral ones are drawn
+// dotted to set them apart from the ownership hierarchy. Allowed network
+// traffic is drawn thick.
+var mermaidArrows = map[string]string{
+	"-DOWN->":        "-->",
+	"-UP->":          "-->",
+	"-RIGHT->":       "-.->",
+	"-LEFT->":        "-.->",
+	trafficConnector: "==>",
+}
+
+func NewMermaid(resource resource.APIResourceList, renderOption RenderOption) Mermaid {
+	elementList, linkList := newGraphModel(resource, renderOption)
+
+	return Mermaid{elementList: elementList, linkList: linkList, renderOption: renderOption}
+}
+
+func (m *Mermaid) Render(w io.Writer) error {
+	fmt.Fprintln(w, "flowchart TD")
+
+	e := m.elementList.Items
+	sort.Slice(e, func(i, j int) bool { return e[i].UniqueId < e[j].UniqueId })
+	if hasSynthesized(e) {
+		fmt.Fprintln(w, "  classDef synthesized stroke-dasharray: 5 5")
+	}
+
+	for _, group := range groupElements(e, m.renderOption.GroupBy) {
+		// Ungrouped objects, e.g. cluster-scoped ones, are drawn outside of
+		// every subgraph.
+		indent := "  "
+		if group.Key != "" {
+			fmt.Fprintf(w, "  subgraph %s[%s]\n", "group_"+sanitizeId(group.Key), mermaidQuote(group.Title))
+			indent = "    "
+		}
+		for _, elem := range group.Elements {
+			class := ""
+			if elem.Source.Synthesized {
+				class = ":::synthesized"
+			}
+			fmt.Fprintf(w, "%s%s[%s]%s\n", indent, elem.UniqueId, mermaidQuote(elem.description(m.renderOption)), class)
+		}
+		if group.Key != "" {
+			fmt.Fprintln(w, "  end")
+		}
+	}
+
+	known := map[string]bool{}
+	for _, elem := range e {
+		known[elem.UniqueId] = true
+	}
+	for _, link := range m.linkList.Items {
+		// Link ends without an element, e.g. "(No backend Service)", a
+		// "(User: ...)" subject or a missing ConfigMap, get a rounded node.
+		for _, id := range []string{link.From, link.To} {
+			if !known[id] {
+				known[id] = true
+				fmt.Fprintf(w, "  %s([%s])\n", mermaidNodeId(id), mermaidQuote(id))
+			}
+		}
+	}
+
+	for _, link := range m.linkList.Items {
+		arrow, ok := mermaidArrows[link.Connector]
+		if !ok {
+			arrow = "-->"
+		}
+		if m.renderOption.ShowLinkLabel && link.Label != "" {
+			arrow += "|" + mermaidQuote(link.Label) + "|"
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", mermaidNodeId(link.From), arrow, mermaidNodeId(link.To))
+	}
+	return nil
+}
+
+// mermaidNodeId maps placeholder link targets such as "(No Target Pod)" to a
+// valid node ID; IDs built by createUniqueId are already safe.
+func mermaidNodeId(id string) string {
+	for i := 0; i < len(id); i++ {
+		if !isIdByte(id[i]) && id[i] != '_' {
+			return "missing" + sanitizeId(id)
+		}
+	}
+	return id
+}
+
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	s = strings.ReplaceAll(s, "\\n", "<br/>")
+	s = strings.ReplaceAll(s, "\"", "#quot;")
+	return "\"" + s + "\""
+}
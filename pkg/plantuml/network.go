@@ -0,0 +1,234 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"strings"
+)
+
+const (
+	ViewAll     = "all"
+	ViewNetwork = "network"
+)
+
+// trafficConnector draws allowed traffic as a dashed blue arrow so it stands
+// apart from the ownership and reference links.
+const trafficConnector = "-[#blue,dashed]->"
+
+func ValidateView(view string) error {
+	switch view {
+	case "", ViewAll, ViewNetwork:
+		return nil
+	}
+	return fmt.Errorf("unknown view %q (must be one of: all, network)", view)
+}
+
+// networkView keeps only the Pods and the traffic allowed between them.
+func networkView(elementList ElementList, linkList LinkList) (ElementList, LinkList) {
+	elements := ElementList{}
+	for _, elem := range elementList.Items {
+		if elem.Kind == "Pod" {
+			elements.Items = append(elements.Items, elem)
+		}
+	}
+	links := LinkList{}
+	for _, link := range linkList.Items {
+		if link.Connector == trafficConnector {
+			links.Items = append(links.Items, link)
+		}
+	}
+	return elements, links
+}
+
+func NetworkPolicyToPod(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "NetworkPolicy" {
+			selector := &res.(*networkingv1.NetworkPolicy).Spec.PodSelector
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind == "Pod" && targetRes.GetNamespace() == res.GetNamespace() {
+					if labelSelectorMatches(selector, targetRes.GetLabels()) {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", ".spec.podSelector", labelSelectorToString(selector)))
+					}
+				}
+			}
+		}
+	}
+	return linkList
+}
+
+func NetworkPolicyIngress(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	namespaceLabels := collectNamespaceLabels(apiList)
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "NetworkPolicy" {
+			policy := res.(*networkingv1.NetworkPolicy)
+			if !hasPolicyType(policy, networkingv1.PolicyTypeIngress) {
+				continue
+			}
+			targets := networkPolicyTargets(apiList, policy)
+			for i, rule := range policy.Spec.Ingress {
+				fieldPath := fmt.Sprintf(".spec.ingress[%d]", i)
+				label := fieldPath + ": " + networkPolicyPortsToString(rule.Ports)
+				for _, peer := range networkPolicyPeers(apiList, namespaceLabels, policy.Namespace, rule.From) {
+					for _, target := range targets {
+						linkList.Items = append(linkList.Items, NewLink(peer, target, trafficConnector, fieldPath, label))
+					}
+				}
+			}
+		}
+	}
+	return dedupLinks(linkList)
+}
+
+func NetworkPolicyEgress(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	namespaceLabels := collectNamespaceLabels(apiList)
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "NetworkPolicy" {
+			policy := res.(*networkingv1.NetworkPolicy)
+			if !hasPolicyType(policy, networkingv1.PolicyTypeEgress) {
+				continue
+			}
+			targets := networkPolicyTargets(apiList, policy)
+			for i, rule := range policy.Spec.Egress {
+				fieldPath := fmt.Sprintf(".spec.egress[%d]", i)
+				label := fieldPath + ": " + networkPolicyPortsToString(rule.Ports)
+				for _, peer := range networkPolicyPeers(apiList, namespaceLabels, policy.Namespace, rule.To) {
+					for _, target := range targets {
+						linkList.Items = append(linkList.Items, NewLink(target, peer, trafficConnector, fieldPath, label))
+					}
+				}
+			}
+		}
+	}
+	return dedupLinks(linkList)
+}
+
+// hasPolicyType applies the defaulting of .spec.policyTypes: Ingress is
+// always enforced, Egress only when egress rules are present.
+func hasPolicyType(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+func networkPolicyTargets(apiList resource.APIResourceList, policy *networkingv1.NetworkPolicy) []string {
+	var targets []string
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "Pod" && res.GetNamespace() == policy.Namespace {
+			if labelSelectorMatches(&policy.Spec.PodSelector, res.GetLabels()) {
+				targets = append(targets, resourceId(res))
+			}
+		}
+	}
+	return targets
+}
+
+// networkPolicyPeers resolves peers to Pod IDs. IP blocks and an empty peer
+// list, which allows all traffic, become placeholder IDs.
+func networkPolicyPeers(apiList resource.APIResourceList, namespaceLabels map[string]map[string]string, namespace string, peers []networkingv1.NetworkPolicyPeer) []string {
+	if len(peers) == 0 {
+		return []string{"(Any)"}
+	}
+
+	var ids []string
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			id := "(ipBlock " + peer.IPBlock.CIDR
+			if len(peer.IPBlock.Except) > 0 {
+				id += " except " + strings.Join(peer.IPBlock.Except, ", ")
+			}
+			ids = append(ids, id+")")
+			continue
+		}
+		for _, res := range apiList.Items {
+			if res.GroupVersionKind().Kind != "Pod" {
+				continue
+			}
+			if peer.NamespaceSelector == nil {
+				if res.GetNamespace() != namespace {
+					continue
+				}
+			} else if !labelSelectorMatches(peer.NamespaceSelector, namespaceLabels[res.GetNamespace()]) {
+				continue
+			}
+			if peer.PodSelector != nil && !labelSelectorMatches(peer.PodSelector, res.GetLabels()) {
+				continue
+			}
+			ids = append(ids, resourceId(res))
+		}
+	}
+	return ids
+}
+
+// collectNamespaceLabels returns the labels of every namespace seen in
+// apiList, including the kubernetes.io/metadata.name label that the API
+// server sets automatically.
+func collectNamespaceLabels(apiList resource.APIResourceList) map[string]map[string]string {
+	namespaceLabels := map[string]map[string]string{}
+	ensure := func(namespace string) map[string]string {
+		if _, ok := namespaceLabels[namespace]; !ok {
+			namespaceLabels[namespace] = map[string]string{"kubernetes.io/metadata.name": namespace}
+		}
+		return namespaceLabels[namespace]
+	}
+
+	for _, res := range apiList.Items {
+		if ns, ok := res.(*corev1.Namespace); ok {
+			l := ensure(ns.Name)
+			for k, v := range ns.Labels {
+				l[k] = v
+			}
+		} else if res.GetNamespace() != "" {
+			ensure(res.GetNamespace())
+		}
+	}
+	return namespaceLabels
+}
+
+func networkPolicyPortsToString(ports []networkingv1.NetworkPolicyPort) string {
+	if len(ports) == 0 {
+		return "all ports"
+	}
+
+	var s []string
+	for _, port := range ports {
+		protocol := string(corev1.ProtocolTCP)
+		if port.Protocol != nil {
+			protocol = string(*port.Protocol)
+		}
+		if port.Port == nil {
+			s = append(s, protocol)
+		} else {
+			s = append(s, protocol+"/"+port.Port.String())
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+func dedupLinks(linkList LinkList) LinkList {
+	deduped := LinkList{}
+	seen := map[string]bool{}
+	for _, link := range linkList.Items {
+		key := link.From + "\x00" + link.To + "\x00" + link.FieldPath + "\x00" + link.Label
+		if !seen[key] {
+			seen[key] = true
+			deduped.Items = append(deduped.Items, link)
+		}
+	}
+	return deduped
+}
@@ -0,0 +1,200 @@
+package plantuml
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+const networkInput = `apiVersion: v1
+kind: Namespace
+metadata: {name: shop, labels: {team: shop}}
+---
+apiVersion: v1
+kind: Namespace
+metadata: {name: ops, labels: {team: ops}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: web, namespace: shop, labels: {app: web}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: db, namespace: shop, labels: {app: db}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: monitor, namespace: ops, labels: {app: monitor}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: web, namespace: ops, labels: {app: web}}
+`
+
+func TestNetworkPolicyPeers(t *testing.T) {
+	list := decodeTestResources(t, networkInput)
+	namespaceLabels := collectNamespaceLabels(list)
+
+	tests := []struct {
+		name  string
+		peers []networkingv1.NetworkPolicyPeer
+		want  []string
+	}{
+		{
+			name: "no peers allow any",
+			want: []string{"(Any)"},
+		},
+		{
+			name:  "podSelector in the policy's namespace",
+			peers: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}},
+			want:  []string{"shop_Pod_db"},
+		},
+		{
+			name:  "empty podSelector",
+			peers: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+			want:  []string{"shop_Pod_web", "shop_Pod_db"},
+		},
+		{
+			name:  "namespaceSelector",
+			peers: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}}},
+			want:  []string{"ops_Pod_monitor", "ops_Pod_web"},
+		},
+		{
+			name:  "namespaceSelector by metadata.name",
+			peers: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "ops"}}}},
+			want:  []string{"ops_Pod_monitor", "ops_Pod_web"},
+		},
+		{
+			name: "namespaceSelector AND podSelector",
+			peers: []networkingv1.NetworkPolicyPeer{{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			}},
+			want: []string{"ops_Pod_web"},
+		},
+		{
+			name: "namespaceSelector OR podSelector",
+			peers: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}},
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			},
+			want: []string{"ops_Pod_monitor", "ops_Pod_web", "shop_Pod_web"},
+		},
+		{
+			name:  "ipBlock",
+			peers: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}},
+			want:  []string{"(ipBlock 10.0.0.0/8)"},
+		},
+		{
+			name:  "ipBlock with except",
+			peers: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16", "10.2.0.0/16"}}}},
+			want:  []string{"(ipBlock 10.0.0.0/8 except 10.1.0.0/16, 10.2.0.0/16)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := networkPolicyPeers(list, namespaceLabels, "shop", tt.peers)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got peers %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasPolicyType(t *testing.T) {
+	egressRules := []networkingv1.NetworkPolicyEgressRule{{}}
+
+	tests := []struct {
+		name        string
+		spec        networkingv1.NetworkPolicySpec
+		wantIngress bool
+		wantEgress  bool
+	}{
+		{
+			name:        "default without egress rules",
+			wantIngress: true,
+		},
+		{
+			name:        "default with egress rules",
+			spec:        networkingv1.NetworkPolicySpec{Egress: egressRules},
+			wantIngress: true,
+			wantEgress:  true,
+		},
+		{
+			name:       "Egress only",
+			spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}},
+			wantEgress: true,
+		},
+		{
+			name:        "Ingress only with egress rules",
+			spec:        networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, Egress: egressRules},
+			wantIngress: true,
+		},
+		{
+			name:        "Ingress and Egress",
+			spec:        networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}},
+			wantIngress: true,
+			wantEgress:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &networkingv1.NetworkPolicy{Spec: tt.spec}
+			if got := hasPolicyType(policy, networkingv1.PolicyTypeIngress); got != tt.wantIngress {
+				t.Errorf("Ingress: got %v, want %v", got, tt.wantIngress)
+			}
+			if got := hasPolicyType(policy, networkingv1.PolicyTypeEgress); got != tt.wantEgress {
+				t.Errorf("Egress: got %v, want %v", got, tt.wantEgress)
+			}
+		})
+	}
+}
+
+func TestNetworkPolicyDirection(t *testing.T) {
+	list := decodeTestResources(t, networkInput+`---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: web, namespace: shop}
+spec:
+  podSelector: {matchLabels: {app: web}}
+  policyTypes: [Ingress, Egress]
+  ingress:
+  - from:
+    - namespaceSelector: {matchLabels: {team: ops}}
+      podSelector: {matchLabels: {app: monitor}}
+  egress:
+  - to:
+    - podSelector: {matchLabels: {app: db}}
+    ports:
+    - {port: 5432}
+`)
+
+	tests := []struct {
+		name  string
+		links LinkList
+		want  []string
+	}{
+		{
+			name:  "ingress from the peer to the selected Pods",
+			links: NetworkPolicyIngress(list),
+			want:  []string{"ops_Pod_monitor -> shop_Pod_web: .spec.ingress[0]: all ports"},
+		},
+		{
+			name:  "egress from the selected Pods to the peer",
+			links: NetworkPolicyEgress(list),
+			want:  []string{"shop_Pod_web -> shop_Pod_db: .spec.egress[0]: TCP/5432"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, link := range tt.links.Items {
+				got = append(got, link.From+" -> "+link.To+": "+link.Label)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got links %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
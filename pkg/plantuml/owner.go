@@ -0,0 +1,45 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// OwnerToDependent links an owner to the objects that name it in
+// .metadata.ownerReferences, e.g. the real ReplicaSets of a Deployment in a
+// dump of a cluster.
+func OwnerToDependent(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		accessor, err := meta.Accessor(res)
+		if err != nil {
+			continue
+		}
+		for i, ownerRef := range accessor.GetOwnerReferences() {
+			fieldPath := fmt.Sprintf(".metadata.ownerReferences[%d]", i)
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind != ownerRef.Kind || targetRes.GetName() != ownerRef.Name {
+					continue
+				}
+				if !resource.IsOwnedBy(res, targetRes) {
+					continue
+				}
+				from := resourceId(targetRes)
+				to := resourceId(res)
+				label := fmt.Sprintf("%s.kind: %s\\n%s.name: %s", fieldPath, ownerRef.Kind, fieldPath, ownerRef.Name)
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", fieldPath, label))
+			}
+		}
+	}
+	return linkList
+}
+
+// isSelectorChild reports whether target is an object of kind in the
+// namespace of parent that parent's selector rule should consider. Objects
+// with ownerReferences were created by a controller and are linked by
+// OwnerToDependent instead, so that they are not linked twice.
+func isSelectorChild(target resource.APIResource, kind string, parent resource.APIResource) bool {
+	return target.GroupVersionKind().Kind == kind && target.GetNamespace() == parent.GetNamespace() && !resource.HasOwnerReferences(target)
+}
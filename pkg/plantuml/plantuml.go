@@ -3,17 +3,39 @@ package plantuml
 import (
 	"fmt"
 	"github.com/gashirar/kuml/pkg/resource"
+	"io"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
 	extenshionsv1beta1 "k8s.io/api/extensions/v1beta1"
-	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sort"
 	"strings"
 )
 
 type RenderOption struct {
-	showLinkLabel bool
+	ShowLinkLabel bool
+	GroupBy       string
+	Style         string
+	IconInclude   string
+	ShowRoleRules bool
+	View          string
+	HideUnknown   bool
+	// Rules and SkipRules select link rules by name. All registered rules
+	// are evaluated when Rules is empty.
+	Rules     []string
+	SkipRules []string
+}
+
+type Renderer interface {
+	Render(w io.Writer) error
 }
 
 type PlantUML struct {
@@ -22,29 +44,88 @@ type PlantUML struct {
 	renderOption RenderOption
 }
 
-func (u *PlantUML) Render() {
-	fmt.Println("@startuml")
+func (u *PlantUML) Render(w io.Writer) error {
+	fmt.Fprintln(w, "@startuml")
+	if u.renderOption.Style == StyleIcons {
+		iconInclude := u.renderOption.IconInclude
+		if iconInclude == "" {
+			iconInclude = DefaultIconInclude
+		}
+		fmt.Fprintf(w, "!include %s\n", iconInclude)
+	}
 
 	e := u.elementList.Items
 	sort.Slice(e, func(i, j int) bool { return e[i].UniqueId < e[j].UniqueId })
-	for _, elem := range e {
-		elem.Render()
+	if hasSynthesized(e) {
+		fmt.Fprintln(w, "skinparam rectangle<<synthesized>> {")
+		fmt.Fprintln(w, "  BorderStyle dashed")
+		fmt.Fprintln(w, "}")
+	}
+	for _, group := range groupElements(e, u.renderOption.GroupBy) {
+		if group.Key == "" {
+			for _, elem := range group.Elements {
+				elem.Render(w, u.renderOption)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "package \"%s\" {\n", escapeLabel(group.Title))
+		for _, elem := range group.Elements {
+			fmt.Fprint(w, "  ")
+			elem.Render(w, u.renderOption)
+		}
+		fmt.Fprintln(w, "}")
 	}
 
 	for _, link := range u.linkList.Items {
-		link.Render()
+		link.Render(w, u.renderOption)
 	}
 
-	fmt.Println("@enduml")
+	fmt.Fprintln(w, "@enduml")
+	return nil
 }
 
 type Element struct {
 	UniqueId    string
+	APIVersion  string
+	Kind        string
+	Namespace   string
+	Name        string
+	Labels      map[string]string
+	Source      resource.Source
 	Description string
+	// Details holds extra description lines that are only rendered on
+	// request, e.g. the rules of a Role with --show-role-rules.
+	Details []string
 }
 
-func (e *Element) Render() {
-	fmt.Printf("rectangle \"%s\" as %s\n", e.Description, e.UniqueId)
+func (e *Element) description(option RenderOption) string {
+	if option.ShowRoleRules && len(e.Details) > 0 {
+		return e.Description + "\\n" + strings.Join(e.Details, "\\n")
+	}
+	return e.Description
+}
+
+func (e *Element) Render(w io.Writer, option RenderOption) {
+	description := e.description(option)
+	if sprite, ok := kindSprites[e.Kind]; ok && option.Style == StyleIcons {
+		description = fmt.Sprintf("<$%s>\\n%s", sprite, description)
+	}
+	stereotype := ""
+	if e.Source.Synthesized {
+		stereotype = " <<synthesized>>"
+	}
+	fmt.Fprintf(w, "rectangle \"%s\" as %s%s\n", description, e.UniqueId, stereotype)
+}
+
+// hasSynthesized reports whether any element was synthesized from the
+// template of a workload rather than read from the input.
+func hasSynthesized(elements []Element) bool {
+	for _, elem := range elements {
+		if elem.Source.Synthesized {
+			return true
+		}
+	}
+	return false
 }
 
 type ElementList struct {
@@ -55,30 +136,54 @@ type Link struct {
 	From      string
 	To        string
 	Connector string
+	Rule      string
+	FieldPath string
 	Label     string
 }
 
-func (l Link) Render() {
-	fmt.Printf("%s %s %s : \"%s\"\n", l.From, l.Connector, l.To, "")
+func (l Link) Render(w io.Writer, option RenderOption) {
+	label := ""
+	if option.ShowLinkLabel {
+		label = escapeLabel(l.Label)
+	}
+	fmt.Fprintf(w, "%s %s %s : \"%s\"\n", l.From, l.Connector, l.To, label)
 }
 
 type LinkList struct {
 	Items []Link
 }
 
-func NewPlantUML(resource resource.APIResourceList) PlantUML {
+func NewPlantUML(resource resource.APIResourceList, renderOption RenderOption) PlantUML {
+	elementList, linkList := newGraphModel(resource, renderOption)
+
+	return PlantUML{elementList: elementList, linkList: linkList, renderOption: renderOption}
+}
+
+// newGraphModel builds the elements and links shared by every renderer and
+// narrows them down to the requested view.
+func newGraphModel(resource resource.APIResourceList, renderOption RenderOption) (ElementList, LinkList) {
+	if renderOption.HideUnknown {
+		resource = withoutUnstructured(resource)
+	}
 	elementList := NewElementList(resource)
-	linkList := NewLinkList(resource)
+	linkList := evaluateLinkRules(resource, enabledLinkRules(renderOption.Rules, renderOption.SkipRules))
 
-	return PlantUML{elementList: elementList, linkList: linkList}
+	if renderOption.View == ViewNetwork {
+		return networkView(elementList, linkList)
+	}
+	return elementList, linkList
 }
 
-func NewElement(name string, description string) Element {
+func NewElement(uniqueId string, kind string, namespace string, name string, description string) Element {
 	return Element{
-		UniqueId:    name,
+		UniqueId:    uniqueId,
+		Kind:        kind,
+		Namespace:   namespace,
+		Name:        name,
 		Description: description,
 	}
 }
+
 func NewElementList(list resource.APIResourceList) ElementList {
 	var elementList ElementList
 
@@ -86,38 +191,52 @@ func NewElementList(list resource.APIResourceList) ElementList {
 		kind := apiRes.GroupVersionKind().Kind
 		namespace := apiRes.GetNamespace()
 		name := apiRes.GetName()
-		uniqueId := createUniqueId(namespace, kind, name)
+		uniqueId := resourceId(apiRes)
 		description := fmt.Sprintf("kind: %s\\nname: %s", kind, name)
+		source := list.Sources[apiRes]
+		if source.Chart != "" {
+			description += "\\ntemplate: " + source.File
+		}
 
-		elementList.Items = append(elementList.Items, NewElement(uniqueId, description))
+		element := NewElement(uniqueId, kind, namespace, name, description)
+		element.APIVersion = apiRes.GroupVersionKind().GroupVersion().String()
+		element.Labels = apiRes.GetLabels()
+		element.Source = source
+		switch r := apiRes.(type) {
+		case *rbacv1.Role:
+			element.Details = policyRulesSummary(r.Rules)
+		case *rbacv1.ClusterRole:
+			element.Details = policyRulesSummary(r.Rules)
+		}
+		elementList.Items = append(elementList.Items, element)
 	}
 
 	return elementList
 }
 
-func NewLink(from string, to string, connector string, label string) Link {
+func NewLink(from string, to string, connector string, fieldPath string, label string) Link {
 	return Link{
 		From:      from,
 		To:        to,
 		Connector: connector,
+		FieldPath: fieldPath,
 		Label:     label,
 	}
 }
 
 func NewLinkList(resource resource.APIResourceList) LinkList {
+	return evaluateLinkRules(resource, linkRules)
+}
+
+func evaluateLinkRules(resource resource.APIResourceList, rules []LinkRule) LinkList {
 	linkList := LinkList{}
 
-	linkList.Items = append(linkList.Items, DeploymentToReplicaSet(resource).Items...)
-	linkList.Items = append(linkList.Items, ReplicaSetToPod(resource).Items...)
-	linkList.Items = append(linkList.Items, PodToConfigMap(resource).Items...)
-	linkList.Items = append(linkList.Items, PodToSecret(resource).Items...)
-	linkList.Items = append(linkList.Items, ServiceToPod(resource).Items...)
-	linkList.Items = append(linkList.Items, IngressToService(resource).Items...)
-	linkList.Items = append(linkList.Items, PodDisruptionBudgetToPod(resource).Items...)
-	linkList.Items = append(linkList.Items, HorizontalPodAutoscalerToDeployment(resource).Items...)
-	linkList.Items = append(linkList.Items, CronJobToJob(resource).Items...)
-	linkList.Items = append(linkList.Items, JobToPod(resource).Items...)
-	linkList.Items = append(linkList.Items, StatefulSetToPod(resource).Items...)
+	for _, rule := range rules {
+		for _, link := range rule.Links(resource).Items {
+			link.Rule = rule.Name()
+			linkList.Items = append(linkList.Items, link)
+		}
+	}
 	return linkList
 }
 
@@ -127,12 +246,12 @@ func DeploymentToReplicaSet(apiList resource.APIResourceList) LinkList {
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "Deployment" {
 			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "ReplicaSet" {
-					matchLabels := res.(*appsv1.Deployment).Spec.Selector.MatchLabels
-					if IsMapContainsMap(targetRes.GetLabels(), matchLabels) {
-						from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-						to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", labelMapToString(matchLabels)))
+				if isSelectorChild(targetRes, "ReplicaSet", res) {
+					selector := res.(*appsv1.Deployment).Spec.Selector
+					if labelSelectorMatches(selector, targetRes.GetLabels()) {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.selector", labelSelectorToString(selector)))
 					}
 				}
 			}
@@ -147,12 +266,12 @@ func ReplicaSetToPod(apiList resource.APIResourceList) LinkList {
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "ReplicaSet" {
 			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "Pod" {
-					matchLabels := res.(*appsv1.ReplicaSet).Spec.Selector.MatchLabels
-					if IsMapContainsMap(targetRes.GetLabels(), matchLabels) {
-						from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-						to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", labelMapToString(matchLabels)))
+				if isSelectorChild(targetRes, "Pod", res) {
+					selector := res.(*appsv1.ReplicaSet).Spec.Selector
+					if labelSelectorMatches(selector, targetRes.GetLabels()) {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.selector", labelSelectorToString(selector)))
 					}
 				}
 			}
@@ -167,12 +286,32 @@ func StatefulSetToPod(apiList resource.APIResourceList) LinkList {
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "StatefulSet" {
 			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "Pod" {
-					matchLabels := res.(*appsv1.StatefulSet).Spec.Selector.MatchLabels
-					if IsMapContainsMap(targetRes.GetLabels(), matchLabels) {
-						from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-						to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", labelMapToString(matchLabels)))
+				if isSelectorChild(targetRes, "Pod", res) {
+					selector := res.(*appsv1.StatefulSet).Spec.Selector
+					if labelSelectorMatches(selector, targetRes.GetLabels()) {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.selector", labelSelectorToString(selector)))
+					}
+				}
+			}
+		}
+	}
+	return linkList
+}
+
+func DaemonSetToPod(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "DaemonSet" {
+			for _, targetRes := range apiList.Items {
+				if isSelectorChild(targetRes, "Pod", res) {
+					selector := res.(*appsv1.DaemonSet).Spec.Selector
+					if labelSelectorMatches(selector, targetRes.GetLabels()) {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.selector", labelSelectorToString(selector)))
 					}
 				}
 			}
@@ -186,9 +325,13 @@ func CronJobToJob(apiList resource.APIResourceList) LinkList {
 
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "CronJob" {
-			from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-			to := createUniqueId(res.GetNamespace(), "Job", res.GetName())
-			linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ""))
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind == "Job" && isSynthesizedFrom(apiList, targetRes, res) {
+					from := resourceId(res)
+					to := resourceId(targetRes)
+					linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.jobTemplate", ""))
+				}
+			}
 		}
 	}
 	return linkList
@@ -199,35 +342,43 @@ func JobToPod(apiList resource.APIResourceList) LinkList {
 
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "Job" {
-			from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-			to := createUniqueId(res.GetNamespace(), "Pod", res.GetName())
-			linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ""))
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind == "Pod" && isSynthesizedFrom(apiList, targetRes, res) {
+					from := resourceId(res)
+					to := resourceId(targetRes)
+					linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.template", ""))
+				}
+			}
 		}
 	}
 	return linkList
 }
 
+// isSynthesizedFrom reports whether child was synthesized from the template
+// of parent, which may itself be synthesized, like the Job of a CronJob.
+// Objects unwrapped from one List share their Source, so the name of child
+// must match as well.
+func isSynthesizedFrom(apiList resource.APIResourceList, child resource.APIResource, parent resource.APIResource) bool {
+	childSource, parentSource := apiList.Sources[child], apiList.Sources[parent]
+	if !childSource.Synthesized || childSource.File != parentSource.File || childSource.Index != parentSource.Index {
+		return false
+	}
+	name := parent.GetName()
+	if !parentSource.Synthesized {
+		name = resource.SynthesizedName(parent)
+	}
+	return child.GetName() == name && child.GetNamespace() == parent.GetNamespace()
+}
+
 func PodToConfigMap(apiList resource.APIResourceList) LinkList {
 	linkList := LinkList{}
 
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "Pod" {
-			volumes := res.(*corev1.Pod).Spec.Volumes
-			for _, volume := range volumes {
-				if volume.ConfigMap != nil {
-					from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-					to := createUniqueId(res.GetNamespace(), "ConfigMap", volume.ConfigMap.Name)
-					linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.volume.configMap"))
-				}
-				if volume.Projected != nil {
-					for _, projected := range volume.Projected.Sources {
-						if projected.ConfigMap != nil {
-							from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-							to := createUniqueId(res.GetNamespace(), "ConfigMap", projected.ConfigMap.Name)
-							linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.volume.projected.sources.configMap"))
-						}
-					}
-				}
+			for _, ref := range podConfigMapReferences(res.(*corev1.Pod).Spec) {
+				from := resourceId(res)
+				to := createUniqueId(res.GetNamespace(), "ConfigMap", ref.name)
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ref.path, ref.label))
 			}
 		}
 	}
@@ -239,26 +390,106 @@ func PodToSecret(apiList resource.APIResourceList) LinkList {
 
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "Pod" {
-			volumes := res.(*corev1.Pod).Spec.Volumes
-			for _, volume := range volumes {
-				if volume.Secret != nil {
-					from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-					to := createUniqueId(res.GetNamespace(), "Secret", volume.Secret.SecretName)
-					linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.volume.secret"))
+			for _, ref := range podSecretReferences(res.(*corev1.Pod).Spec) {
+				from := resourceId(res)
+				to := createUniqueId(res.GetNamespace(), "Secret", ref.name)
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ref.path, ref.label))
+			}
+		}
+	}
+	return linkList
+}
+
+type podReference struct {
+	name  string
+	path  string
+	label string
+}
+
+func podConfigMapReferences(spec corev1.PodSpec) []podReference {
+	var refs []podReference
+
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			path := fmt.Sprintf(".spec.volumes[%s].configMap", volume.Name)
+			refs = append(refs, podReference{name: volume.ConfigMap.Name, path: path, label: path})
+		}
+		if volume.Projected != nil {
+			for _, projected := range volume.Projected.Sources {
+				if projected.ConfigMap != nil {
+					path := fmt.Sprintf(".spec.volumes[%s].projected.sources.configMap", volume.Name)
+					refs = append(refs, podReference{name: projected.ConfigMap.Name, path: path, label: path})
 				}
-				if volume.Projected != nil {
-					for _, projected := range volume.Projected.Sources {
-						if projected.Secret != nil {
-							from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-							to := createUniqueId(res.GetNamespace(), "Secret", projected.Secret.Name)
-							linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.volume.projected.sources.secret"))
-						}
-					}
+			}
+		}
+	}
+	for _, group := range podContainerGroups(spec) {
+		for _, container := range group.containers {
+			for _, envFrom := range container.EnvFrom {
+				if envFrom.ConfigMapRef != nil {
+					path := fmt.Sprintf("%s[%s].envFrom.configMapRef", group.path, container.Name)
+					refs = append(refs, podReference{name: envFrom.ConfigMapRef.Name, path: path, label: path})
+				}
+			}
+			for _, env := range container.Env {
+				if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+					path := fmt.Sprintf("%s[%s].env[%s].valueFrom.configMapKeyRef", group.path, container.Name, env.Name)
+					label := path + ".key: " + env.ValueFrom.ConfigMapKeyRef.Key
+					refs = append(refs, podReference{name: env.ValueFrom.ConfigMapKeyRef.Name, path: path, label: label})
 				}
 			}
 		}
 	}
-	return linkList
+	return refs
+}
+
+func podSecretReferences(spec corev1.PodSpec) []podReference {
+	var refs []podReference
+
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			path := fmt.Sprintf(".spec.volumes[%s].secret", volume.Name)
+			refs = append(refs, podReference{name: volume.Secret.SecretName, path: path, label: path})
+		}
+		if volume.Projected != nil {
+			for _, projected := range volume.Projected.Sources {
+				if projected.Secret != nil {
+					path := fmt.Sprintf(".spec.volumes[%s].projected.sources.secret", volume.Name)
+					refs = append(refs, podReference{name: projected.Secret.Name, path: path, label: path})
+				}
+			}
+		}
+	}
+	for _, group := range podContainerGroups(spec) {
+		for _, container := range group.containers {
+			for _, envFrom := range container.EnvFrom {
+				if envFrom.SecretRef != nil {
+					path := fmt.Sprintf("%s[%s].envFrom.secretRef", group.path, container.Name)
+					refs = append(refs, podReference{name: envFrom.SecretRef.Name, path: path, label: path})
+				}
+			}
+			for _, env := range container.Env {
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+					path := fmt.Sprintf("%s[%s].env[%s].valueFrom.secretKeyRef", group.path, container.Name, env.Name)
+					label := path + ".key: " + env.ValueFrom.SecretKeyRef.Key
+					refs = append(refs, podReference{name: env.ValueFrom.SecretKeyRef.Name, path: path, label: label})
+				}
+			}
+		}
+	}
+	return refs
+}
+
+type podContainerGroup struct {
+	path       string
+	containers []corev1.Container
+}
+
+func podContainerGroups(spec corev1.PodSpec) []podContainerGroup {
+	return []podContainerGroup{
+		{path: ".spec.initContainers", containers: spec.InitContainers},
+		{path: ".spec.containers", containers: spec.Containers},
+	}
 }
 
 func ServiceToPod(apiList resource.APIResourceList) LinkList {
@@ -267,12 +498,12 @@ func ServiceToPod(apiList resource.APIResourceList) LinkList {
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "Service" {
 			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "Pod" {
+				if targetRes.GroupVersionKind().Kind == "Pod" && targetRes.GetNamespace() == res.GetNamespace() {
 					matchLabels := res.(*corev1.Service).Spec.Selector
-					if IsMapContainsMap(targetRes.GetLabels(), matchLabels) {
-						from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-						to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-						linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", labelMapToString(matchLabels)))
+					if len(matchLabels) > 0 && IsMapContainsMap(targetRes.GetLabels(), matchLabels) {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", ".spec.selector", labelMapToString(matchLabels)))
 					}
 				}
 			}
@@ -287,21 +518,21 @@ func PodDisruptionBudgetToPod(apiList resource.APIResourceList) LinkList {
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "PodDisruptionBudget" {
 			matched := false
-			matchLabels := res.(*policyv1beta1.PodDisruptionBudget).Spec.Selector.MatchLabels
+			selector := res.(*policyv1.PodDisruptionBudget).Spec.Selector
 			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "Pod" {
-					if IsMapContainsMap(targetRes.GetLabels(), matchLabels) {
+				if targetRes.GroupVersionKind().Kind == "Pod" && targetRes.GetNamespace() == res.GetNamespace() {
+					if labelSelectorMatches(selector, targetRes.GetLabels()) {
 						matched = true
-						from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-						to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-						linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", labelMapToString(matchLabels)))
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", ".spec.selector", labelSelectorToString(selector)))
 					}
 				}
 			}
 			if !matched {
-				from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
+				from := resourceId(res)
 				to := "(No Target Pod)"
-				linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", labelMapToString(matchLabels)))
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", ".spec.selector", labelSelectorToString(selector)))
 			}
 		}
 	}
@@ -316,20 +547,20 @@ func HorizontalPodAutoscalerToDeployment(apiList resource.APIResourceList) LinkL
 			matched := false
 			scaleTargetRef := res.(*autoscalingv1.HorizontalPodAutoscaler).Spec.ScaleTargetRef
 			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "Deployment" {
+				if targetRes.GroupVersionKind().Kind == "Deployment" && targetRes.GetNamespace() == res.GetNamespace() {
 					if scaleTargetRef.Name == targetRes.GetName() {
 						matched = true
-						from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-						to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
+						from := resourceId(res)
+						to := resourceId(targetRes)
 						label := fmt.Sprintf(".spec.scaleTargetRef.kind: %s\\n.spec.scaleTargetRef.name: %s", targetRes.GroupVersionKind().Kind, targetRes.GetName())
-						linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", label))
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", ".spec.scaleTargetRef", label))
 					}
 				}
 			}
 			if !matched {
-				from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
+				from := resourceId(res)
 				to := "(No Target Deployment)"
-				linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", ""))
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-LEFT->", ".spec.scaleTargetRef", ""))
 			}
 		}
 	}
@@ -341,61 +572,195 @@ func IngressToService(apiList resource.APIResourceList) LinkList {
 
 	for _, res := range apiList.Items {
 		if res.GroupVersionKind().Kind == "Ingress" {
-			for _, targetRes := range apiList.Items {
-				if targetRes.GroupVersionKind().Kind == "Service" {
-					ing := res.(*extenshionsv1beta1.Ingress)
-					svc := targetRes.(*corev1.Service)
-					if ing.Spec.Backend != nil && ing.Spec.Backend.ServiceName == svc.Name {
-						matched := false
-						for _, port := range svc.Spec.Ports {
-							if ing.Spec.Backend.ServicePort.StrVal == port.Name ||
-								ing.Spec.Backend.ServicePort.IntVal == port.Port {
+			for _, backend := range ingressBackends(res) {
+				label := fmt.Sprintf("%s: %s\\n%s: %s", backend.serviceNamePath, backend.serviceName, backend.servicePortPath, backend.servicePort.String())
+				if className := ingressClassName(res); className != "" {
+					label += fmt.Sprintf("\\n.spec.ingressClassName: %s", className)
+				}
+				matched := false
+				for _, targetRes := range apiList.Items {
+					if targetRes.GroupVersionKind().Kind == "Service" && targetRes.GetNamespace() == res.GetNamespace() && targetRes.GetName() == backend.serviceName {
+						for _, port := range targetRes.(*corev1.Service).Spec.Ports {
+							if isServicePortMatched(backend.servicePort, port) {
 								matched = true
-								from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-								to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-								label := fmt.Sprintf(".spec.backend.serviceName: %s\\n.spec.backend.servicePort: %s", ing.Spec.Backend.ServiceName, ing.Spec.Backend.ServicePort.String())
-								linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", label))
-							}
-						}
-						if !matched {
-							from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-							to := "(No backend Service)"
-							label := fmt.Sprintf(".spec.backend.serviceName: %s\\n.spec.backend.servicePort: %s", ing.Spec.Backend.ServiceName, ing.Spec.Backend.ServicePort.String())
-							linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", label))
-						}
-					}
-					if ing.Spec.Rules != nil {
-						for _, rule := range ing.Spec.Rules {
-							for _, path := range rule.HTTP.Paths {
-								if path.Backend.ServiceName == svc.Name {
-									matched := false
-									for _, port := range svc.Spec.Ports {
-										if path.Backend.ServicePort.StrVal == port.Name ||
-											path.Backend.ServicePort.IntVal == port.Port {
-											matched = true
-											from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-											to := createUniqueId(targetRes.GetNamespace(), targetRes.GroupVersionKind().Kind, targetRes.GetName())
-											label := fmt.Sprintf(".spec.rules.http.paths.backend.serviceName: %s\\n.spec.rules.http.paths.backend.servicePort: %s", path.Backend.ServiceName, path.Backend.ServicePort.String())
-											linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", label))
-										}
-									}
-									if !matched {
-										from := createUniqueId(res.GetNamespace(), res.GroupVersionKind().Kind, res.GetName())
-										to := "(No backend Service)"
-										label := fmt.Sprintf(".spec.backend.serviceName: %s\\n.spec.backend.servicePort: %s", path.Backend.ServiceName, path.Backend.ServicePort.String())
-										linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", label))
-									}
-								}
+								from := resourceId(res)
+								to := resourceId(targetRes)
+								linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", backend.serviceNamePath, label))
 							}
 						}
 					}
 				}
+				if !matched {
+					from := resourceId(res)
+					to := "(No backend Service)"
+					linkList.Items = append(linkList.Items, NewLink(from, to, "-RIGHT->", backend.serviceNamePath, label))
+				}
+			}
+		}
+	}
+	return linkList
+}
+
+func IngressToIngressClass(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "Ingress" {
+			className := ingressClassName(res)
+			if className == "" {
+				continue
+			}
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind == "IngressClass" && targetRes.GetName() == className {
+					from := resourceId(res)
+					to := resourceId(targetRes)
+					linkList.Items = append(linkList.Items, NewLink(from, to, "-UP->", ".spec.ingressClassName", ".spec.ingressClassName: "+className))
+				}
 			}
 		}
 	}
 	return linkList
 }
 
+type ingressBackend struct {
+	serviceName     string
+	servicePort     intstr.IntOrString
+	serviceNamePath string
+	servicePortPath string
+}
+
+func ingressBackends(res resource.APIResource) []ingressBackend {
+	var backends []ingressBackend
+
+	switch ing := res.(type) {
+	case *networkingv1.Ingress:
+		appendBackend := func(backend *networkingv1.IngressBackend, path string) {
+			if backend == nil || backend.Service == nil {
+				return
+			}
+			b := ingressBackend{
+				serviceName:     backend.Service.Name,
+				serviceNamePath: path + ".service.name",
+			}
+			if backend.Service.Port.Name != "" {
+				b.servicePort = intstr.FromString(backend.Service.Port.Name)
+				b.servicePortPath = path + ".service.port.name"
+			} else {
+				b.servicePort = intstr.FromInt(int(backend.Service.Port.Number))
+				b.servicePortPath = path + ".service.port.number"
+			}
+			backends = append(backends, b)
+		}
+		appendBackend(ing.Spec.DefaultBackend, ".spec.defaultBackend")
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for i := range rule.HTTP.Paths {
+				appendBackend(&rule.HTTP.Paths[i].Backend, ".spec.rules.http.paths.backend")
+			}
+		}
+	case *networkingv1beta1.Ingress:
+		appendBackend := func(backend *networkingv1beta1.IngressBackend, path string) {
+			if backend == nil || backend.ServiceName == "" {
+				return
+			}
+			backends = append(backends, ingressBackend{
+				serviceName:     backend.ServiceName,
+				servicePort:     backend.ServicePort,
+				serviceNamePath: path + ".serviceName",
+				servicePortPath: path + ".servicePort",
+			})
+		}
+		appendBackend(ing.Spec.Backend, ".spec.backend")
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for i := range rule.HTTP.Paths {
+				appendBackend(&rule.HTTP.Paths[i].Backend, ".spec.rules.http.paths.backend")
+			}
+		}
+	case *extenshionsv1beta1.Ingress:
+		appendBackend := func(backend *extenshionsv1beta1.IngressBackend, path string) {
+			if backend == nil || backend.ServiceName == "" {
+				return
+			}
+			backends = append(backends, ingressBackend{
+				serviceName:     backend.ServiceName,
+				servicePort:     backend.ServicePort,
+				serviceNamePath: path + ".serviceName",
+				servicePortPath: path + ".servicePort",
+			})
+		}
+		appendBackend(ing.Spec.Backend, ".spec.backend")
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for i := range rule.HTTP.Paths {
+				appendBackend(&rule.HTTP.Paths[i].Backend, ".spec.rules.http.paths.backend")
+			}
+		}
+	}
+	return backends
+}
+
+func ingressClassName(res resource.APIResource) string {
+	var className *string
+
+	switch ing := res.(type) {
+	case *networkingv1.Ingress:
+		className = ing.Spec.IngressClassName
+	case *networkingv1beta1.Ingress:
+		className = ing.Spec.IngressClassName
+	case *extenshionsv1beta1.Ingress:
+		className = ing.Spec.IngressClassName
+	}
+	if className != nil {
+		return *className
+	}
+	return ""
+}
+
+func isServicePortMatched(backendPort intstr.IntOrString, port corev1.ServicePort) bool {
+	if backendPort.Type == intstr.String {
+		return backendPort.StrVal == port.Name
+	}
+	return backendPort.IntVal == port.Port
+}
+
+// labelSelectorMatches reports whether selector selects labelSet. A missing
+// or invalid selector selects nothing.
+func labelSelectorMatches(selector *metav1.LabelSelector, labelSet map[string]string) bool {
+	if selector == nil {
+		return false
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(labelSet))
+}
+
+func labelSelectorToString(selector *metav1.LabelSelector) string {
+	if selector == nil {
+		return ""
+	}
+
+	labelString := labelMapToString(selector.MatchLabels)
+	for _, expr := range selector.MatchExpressions {
+		if labelString != "" {
+			labelString += "\\n"
+		}
+		labelString += expr.Key + " " + string(expr.Operator)
+		if len(expr.Values) > 0 {
+			labelString += " (" + strings.Join(expr.Values, ", ") + ")"
+		}
+	}
+	return labelString
+}
+
 func IsMapContainsMap(mainMap map[string]string, subMap map[string]string) bool {
 	for sk, sv := range subMap {
 		isContains := false
@@ -411,17 +776,62 @@ func IsMapContainsMap(mainMap map[string]string, subMap map[string]string) bool
 	return true
 }
 
+// resourceId returns the ID of res. Kinds kuml has no schema for, e.g. a
+// knative Service, get their API group appended to the kind, so that they
+// do not collide with a core kind of the same name.
+func resourceId(res resource.APIResource) string {
+	gvk := res.GroupVersionKind()
+	kind := gvk.Kind
+	if _, ok := res.(*unstructured.Unstructured); ok && gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+	return createUniqueId(res.GetNamespace(), kind, res.GetName())
+}
+
 func createUniqueId(namespace string, kind string, name string) string {
 	if namespace == "" {
-		namespace = "default"
+		namespace = "cluster"
 	}
-	return namespace + "_" + kind + "_" + strings.ReplaceAll(name, "-", "_")
+	return sanitizeId(namespace) + "_" + sanitizeId(kind) + "_" + sanitizeId(name)
+}
+
+// sanitizeId escapes every byte that is not valid in a PlantUML alias or
+// Mermaid node ID, including "_" itself, as "_" and two hex digits, e.g.
+// "a-b.c" becomes "a_2db_2ec". Unlike replacing them with a single "_", this
+// keeps IDs of different objects apart.
+func sanitizeId(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if isIdByte(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+func isIdByte(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
 }
 
 func labelMapToString(label map[string]string) string {
+	keys := make([]string, 0, len(label))
+	for k := range label {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	labelString := ""
-	for k, v := range label {
-		labelString += string(k) + " : " + string(v) + "\\n"
+	for _, k := range keys {
+		labelString += k + " : " + label[k] + "\\n"
 	}
-	return strings.TrimRight(labelString, "\\n")
+	return strings.TrimSuffix(labelString, "\\n")
+}
+
+func escapeLabel(label string) string {
+	label = strings.ReplaceAll(label, "\r", "")
+	label = strings.ReplaceAll(label, "\n", "\\n")
+	return strings.ReplaceAll(label, "\"", "<U+0022>")
 }
@@ -0,0 +1,66 @@
+package plantuml
+
+import (
+	"bytes"
+	"flag"
+	"github.com/gashirar/kuml/pkg/resource"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func readTestResources(t *testing.T, name string) resource.APIResourceList {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	documents, errs := resource.ReadYaml(resource.ReadOptions{}, path)
+	if len(errs) > 0 {
+		t.Fatalf("reading %s: %v", path, errs)
+	}
+	list, errs := resource.NewAPIResourceList(documents, "default")
+	if len(errs) > 0 {
+		t.Fatalf("parsing %s: %v", path, errs)
+	}
+	return list
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update to accept it):\n%s", path, got)
+	}
+}
+
+func TestPlantUMLShowLinkLabel(t *testing.T) {
+	list := readTestResources(t, "link-label.yaml")
+
+	tests := []struct {
+		name          string
+		showLinkLabel bool
+		golden        string
+	}{
+		{name: "hidden", showLinkLabel: false, golden: "link-label-hidden.puml"},
+		{name: "shown", showLinkLabel: true, golden: "link-label-shown.puml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pUml := NewPlantUML(list, RenderOption{ShowLinkLabel: tt.showLinkLabel, GroupBy: GroupByNamespace, Style: StyleRectangles})
+			var out bytes.Buffer
+			if err := pUml.Render(&out); err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.golden, out.Bytes())
+		})
+	}
+}
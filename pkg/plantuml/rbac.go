@@ -0,0 +1,131 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"strings"
+)
+
+func PodToServiceAccount(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "Pod" {
+			spec := res.(*corev1.Pod).Spec
+			fieldPath := ".spec.serviceAccountName"
+			serviceAccountName := spec.ServiceAccountName
+			if serviceAccountName == "" && spec.DeprecatedServiceAccount != "" {
+				fieldPath = ".spec.serviceAccount"
+				serviceAccountName = spec.DeprecatedServiceAccount
+			}
+			if serviceAccountName == "" {
+				continue
+			}
+			from := resourceId(res)
+			to := createUniqueId(res.GetNamespace(), "ServiceAccount", serviceAccountName)
+			linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", fieldPath, fieldPath+": "+serviceAccountName))
+		}
+	}
+	return linkList
+}
+
+func RoleBindingToRole(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		switch binding := res.(type) {
+		case *rbacv1.RoleBinding:
+			// A RoleBinding may grant a ClusterRole within its own namespace.
+			namespace := binding.Namespace
+			if binding.RoleRef.Kind == "ClusterRole" {
+				namespace = ""
+			}
+			from := resourceId(res)
+			to := createUniqueId(namespace, binding.RoleRef.Kind, binding.RoleRef.Name)
+			linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".roleRef", roleRefToString(binding.RoleRef)))
+		case *rbacv1.ClusterRoleBinding:
+			from := resourceId(res)
+			to := createUniqueId("", binding.RoleRef.Kind, binding.RoleRef.Name)
+			linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".roleRef", roleRefToString(binding.RoleRef)))
+		}
+	}
+	return linkList
+}
+
+func RoleBindingToSubject(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		var subjects []rbacv1.Subject
+		switch binding := res.(type) {
+		case *rbacv1.RoleBinding:
+			subjects = binding.Subjects
+		case *rbacv1.ClusterRoleBinding:
+			subjects = binding.Subjects
+		default:
+			continue
+		}
+
+		from := resourceId(res)
+		for i, subject := range subjects {
+			fieldPath := fmt.Sprintf(".subjects[%d]", i)
+			var to string
+			if subject.Kind == "ServiceAccount" {
+				// Subjects are the one place where a reference explicitly
+				// crosses namespaces.
+				namespace := subject.Namespace
+				if namespace == "" {
+					namespace = res.GetNamespace()
+				}
+				to = createUniqueId(namespace, subject.Kind, subject.Name)
+			} else {
+				to = fmt.Sprintf("(%s: %s)", subject.Kind, subject.Name)
+			}
+			linkList.Items = append(linkList.Items, NewLink(from, to, "-UP->", fieldPath, subjectToString(subject)))
+		}
+	}
+	return linkList
+}
+
+func roleRefToString(roleRef rbacv1.RoleRef) string {
+	return fmt.Sprintf(".roleRef.kind: %s\\n.roleRef.name: %s", roleRef.Kind, roleRef.Name)
+}
+
+func subjectToString(subject rbacv1.Subject) string {
+	label := fmt.Sprintf(".subjects.kind: %s\\n.subjects.name: %s", subject.Kind, subject.Name)
+	if subject.Namespace != "" {
+		label += "\\n.subjects.namespace: " + subject.Namespace
+	}
+	return label
+}
+
+// policyRulesSummary renders one line per rule, e.g.
+// "deployments.apps, pods: get, list".
+func policyRulesSummary(rules []rbacv1.PolicyRule) []string {
+	var lines []string
+	for _, rule := range rules {
+		var targets []string
+		for _, r := range rule.Resources {
+			for _, group := range rule.APIGroups {
+				if group == "" {
+					targets = append(targets, r)
+				} else {
+					targets = append(targets, r+"."+group)
+				}
+			}
+			if len(rule.APIGroups) == 0 {
+				targets = append(targets, r)
+			}
+		}
+		targets = append(targets, rule.NonResourceURLs...)
+		if len(rule.ResourceNames) > 0 {
+			for i := range targets {
+				targets[i] += "[" + strings.Join(rule.ResourceNames, "|") + "]"
+			}
+		}
+		lines = append(lines, strings.Join(targets, ", ")+": "+strings.Join(rule.Verbs, ", "))
+	}
+	return lines
+}
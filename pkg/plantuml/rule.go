@@ -0,0 +1,131 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	"strings"
+)
+
+// AnyKind is used in SourceKinds and TargetKinds by rules that are not
+// limited to particular kinds.
+const AnyKind = "*"
+
+// LinkRule derives links between the objects of a resource list. Rules are
+// evaluated by NewLinkList in the order they were registered, and every link
+// is stamped with the name of the rule that produced it.
+type LinkRule interface {
+	Name() string
+	SourceKinds() []string
+	TargetKinds() []string
+	Links(apiList resource.APIResourceList) LinkList
+}
+
+type funcLinkRule struct {
+	name        string
+	sourceKinds []string
+	targetKinds []string
+	fn          func(resource.APIResourceList) LinkList
+}
+
+func (r *funcLinkRule) Name() string          { return r.name }
+func (r *funcLinkRule) SourceKinds() []string { return r.sourceKinds }
+func (r *funcLinkRule) TargetKinds() []string { return r.targetKinds }
+func (r *funcLinkRule) Links(apiList resource.APIResourceList) LinkList {
+	return r.fn(apiList)
+}
+
+// NewLinkRule wraps a rule function such as PodToSecret in a LinkRule.
+func NewLinkRule(name string, sourceKinds []string, targetKinds []string, fn func(resource.APIResourceList) LinkList) LinkRule {
+	return &funcLinkRule{name: name, sourceKinds: sourceKinds, targetKinds: targetKinds, fn: fn}
+}
+
+var linkRules = []LinkRule{
+	NewLinkRule("DeploymentToReplicaSet", []string{"Deployment"}, []string{"ReplicaSet"}, DeploymentToReplicaSet),
+	NewLinkRule("ReplicaSetToPod", []string{"ReplicaSet"}, []string{"Pod"}, ReplicaSetToPod),
+	NewLinkRule("PodToConfigMap", []string{"Pod"}, []string{"ConfigMap"}, PodToConfigMap),
+	NewLinkRule("PodToSecret", []string{"Pod"}, []string{"Secret"}, PodToSecret),
+	NewLinkRule("ServiceToPod", []string{"Service"}, []string{"Pod"}, ServiceToPod),
+	NewLinkRule("IngressToService", []string{"Ingress"}, []string{"Service"}, IngressToService),
+	NewLinkRule("IngressToIngressClass", []string{"Ingress"}, []string{"IngressClass"}, IngressToIngressClass),
+	NewLinkRule("PodDisruptionBudgetToPod", []string{"PodDisruptionBudget"}, []string{"Pod"}, PodDisruptionBudgetToPod),
+	NewLinkRule("HorizontalPodAutoscalerToDeployment", []string{"HorizontalPodAutoscaler"}, []string{"Deployment"}, HorizontalPodAutoscalerToDeployment),
+	NewLinkRule("CronJobToJob", []string{"CronJob"}, []string{"Job"}, CronJobToJob),
+	NewLinkRule("JobToPod", []string{"Job"}, []string{"Pod"}, JobToPod),
+	NewLinkRule("StatefulSetToPod", []string{"StatefulSet"}, []string{"Pod"}, StatefulSetToPod),
+	NewLinkRule("DaemonSetToPod", []string{"DaemonSet"}, []string{"Pod"}, DaemonSetToPod),
+	NewLinkRule("PodToPersistentVolumeClaim", []string{"Pod"}, []string{"PersistentVolumeClaim"}, PodToPersistentVolumeClaim),
+	NewLinkRule("PersistentVolumeClaimToPersistentVolume", []string{"PersistentVolumeClaim"}, []string{"PersistentVolume"}, PersistentVolumeClaimToPersistentVolume),
+	NewLinkRule("PersistentVolumeClaimToStorageClass", []string{"PersistentVolumeClaim"}, []string{"StorageClass"}, PersistentVolumeClaimToStorageClass),
+	NewLinkRule("PodToServiceAccount", []string{"Pod"}, []string{"ServiceAccount"}, PodToServiceAccount),
+	NewLinkRule("RoleBindingToRole", []string{"RoleBinding", "ClusterRoleBinding"}, []string{"Role", "ClusterRole"}, RoleBindingToRole),
+	NewLinkRule("RoleBindingToSubject", []string{"RoleBinding", "ClusterRoleBinding"}, []string{"ServiceAccount"}, RoleBindingToSubject),
+	NewLinkRule("NetworkPolicyToPod", []string{"NetworkPolicy"}, []string{"Pod"}, NetworkPolicyToPod),
+	NewLinkRule("NetworkPolicyIngress", []string{"NetworkPolicy"}, []string{"Pod"}, NetworkPolicyIngress),
+	NewLinkRule("NetworkPolicyEgress", []string{"NetworkPolicy"}, []string{"Pod"}, NetworkPolicyEgress),
+	NewLinkRule("OwnerToDependent", []string{AnyKind}, []string{AnyKind}, OwnerToDependent),
+	NewLinkRule("UnstructuredToReference", []string{AnyKind}, []string{AnyKind}, UnstructuredToReference),
+}
+
+// RegisterLinkRule adds rule after the built-in rules, e.g. from a program
+// that uses this package as a library.
+func RegisterLinkRule(rule LinkRule) error {
+	if rule.Name() == "" {
+		return fmt.Errorf("link rule without a name")
+	}
+	if findLinkRule(rule.Name()) != nil {
+		return fmt.Errorf("link rule %q is already registered", rule.Name())
+	}
+	linkRules = append(linkRules, rule)
+	return nil
+}
+
+// LinkRules returns the registered rules in evaluation order.
+func LinkRules() []LinkRule {
+	rules := make([]LinkRule, len(linkRules))
+	copy(rules, linkRules)
+	return rules
+}
+
+func findLinkRule(name string) LinkRule {
+	for _, rule := range linkRules {
+		if rule.Name() == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// ValidateRules reports names that do not belong to a registered rule.
+func ValidateRules(names []string) error {
+	var unknown []string
+	for _, name := range names {
+		if findLinkRule(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown link rule(s): %s (see \"kuml rules list\")", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// enabledLinkRules applies --rules and --skip-rules. An empty rules list
+// enables every rule.
+func enabledLinkRules(rules []string, skipRules []string) []LinkRule {
+	selected := map[string]bool{}
+	for _, name := range rules {
+		selected[name] = true
+	}
+	skipped := map[string]bool{}
+	for _, name := range skipRules {
+		skipped[name] = true
+	}
+
+	var enabled []LinkRule
+	for _, rule := range linkRules {
+		if (len(rules) == 0 || selected[rule.Name()]) && !skipped[rule.Name()] {
+			enabled = append(enabled, rule)
+		}
+	}
+	return enabled
+}
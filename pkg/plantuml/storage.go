@@ -0,0 +1,84 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func PodToPersistentVolumeClaim(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "Pod" {
+			for _, volume := range res.(*corev1.Pod).Spec.Volumes {
+				if volume.PersistentVolumeClaim != nil {
+					fieldPath := fmt.Sprintf(".spec.volumes[%s].persistentVolumeClaim", volume.Name)
+					from := resourceId(res)
+					to := createUniqueId(res.GetNamespace(), "PersistentVolumeClaim", volume.PersistentVolumeClaim.ClaimName)
+					label := fieldPath + ".claimName: " + volume.PersistentVolumeClaim.ClaimName
+					linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", fieldPath, label))
+				}
+			}
+		}
+	}
+	return linkList
+}
+
+func PersistentVolumeClaimToPersistentVolume(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "PersistentVolumeClaim" {
+			pvc := res.(*corev1.PersistentVolumeClaim)
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind == "PersistentVolume" {
+					pv := targetRes.(*corev1.PersistentVolume)
+					fieldPath := ""
+					if pvc.Spec.VolumeName != "" && pvc.Spec.VolumeName == pv.Name {
+						fieldPath = ".spec.volumeName"
+					} else if claimRef := pv.Spec.ClaimRef; claimRef != nil && claimRef.Name == pvc.Name && claimRef.Namespace == pvc.Namespace {
+						fieldPath = "(PersistentVolume).spec.claimRef"
+					}
+					if fieldPath != "" {
+						from := resourceId(res)
+						to := resourceId(targetRes)
+						linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", fieldPath, fieldPath+": "+pv.Name))
+					}
+				}
+			}
+		}
+	}
+	return linkList
+}
+
+func PersistentVolumeClaimToStorageClass(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		if res.GroupVersionKind().Kind == "PersistentVolumeClaim" {
+			// A nil class means the cluster default and "" disables dynamic
+			// provisioning, so only an explicit name points at a StorageClass.
+			storageClassName := res.(*corev1.PersistentVolumeClaim).Spec.StorageClassName
+			if storageClassName == nil || *storageClassName == "" {
+				continue
+			}
+			label := ".spec.storageClassName: " + *storageClassName
+			matched := false
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind == "StorageClass" && targetRes.GetName() == *storageClassName {
+					matched = true
+					from := resourceId(res)
+					to := resourceId(targetRes)
+					linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.storageClassName", label))
+				}
+			}
+			if !matched {
+				from := resourceId(res)
+				to := "(No StorageClass " + *storageClassName + ")"
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ".spec.storageClassName", label))
+			}
+		}
+	}
+	return linkList
+}
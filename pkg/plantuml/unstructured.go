@@ -0,0 +1,154 @@
+package plantuml
+
+import (
+	"fmt"
+	"github.com/gashirar/kuml/pkg/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sort"
+	"strings"
+)
+
+// withoutUnstructured drops the objects of kinds kuml has no schema for.
+func withoutUnstructured(list resource.APIResourceList) resource.APIResourceList {
+	filtered := list
+	filtered.Items = nil
+	for _, res := range list.Items {
+		if _, ok := res.(*unstructured.Unstructured); !ok {
+			filtered.Items = append(filtered.Items, res)
+		}
+	}
+	return filtered
+}
+
+// commonReferenceKinds maps the stem of a reference field to the kind it
+// usually points at, e.g. "secretName", "tlsSecretRef" and "secretKeyRef"
+// all reference a Secret. Longer stems are tried first.
+var commonReferenceKinds = []struct {
+	stem string
+	kind string
+}{
+	{stem: "persistentvolumeclaim", kind: "PersistentVolumeClaim"},
+	{stem: "serviceaccount", kind: "ServiceAccount"},
+	{stem: "storageclass", kind: "StorageClass"},
+	{stem: "ingressclass", kind: "IngressClass"},
+	{stem: "configmap", kind: "ConfigMap"},
+	{stem: "service", kind: "Service"},
+	{stem: "secret", kind: "Secret"},
+	{stem: "claim", kind: "PersistentVolumeClaim"},
+}
+
+// UnstructuredToReference links unstructured objects to the objects they
+// reference through the usual naming patterns: "<x>Name" strings and
+// "<x>Ref" objects with a name and an optional kind and namespace. A link
+// is only drawn when the referenced object is part of the input. Kinds with
+// a declared rule are skipped, their rules replace the guesswork.
+func UnstructuredToReference(apiList resource.APIResourceList) LinkList {
+	linkList := LinkList{}
+
+	for _, res := range apiList.Items {
+		u, ok := res.(*unstructured.Unstructured)
+		if !ok || hasDeclaredRule(res) {
+			continue
+		}
+		var refs []objectReference
+		for _, key := range sortedKeys(u.Object) {
+			if key == "apiVersion" || key == "kind" || key == "metadata" || key == "status" {
+				continue
+			}
+			refs = append(refs, findObjectReferences("."+key, key, u.Object[key])...)
+		}
+
+		from := resourceId(res)
+		for _, ref := range refs {
+			namespace := ref.namespace
+			if resource.IsClusterScoped(ref.kind) {
+				namespace = ""
+			} else if namespace == "" {
+				namespace = res.GetNamespace()
+			}
+			for _, targetRes := range apiList.Items {
+				if targetRes.GroupVersionKind().Kind != ref.kind || targetRes.GetName() != ref.name {
+					continue
+				}
+				if targetRes.GetNamespace() != namespace {
+					continue
+				}
+				to := resourceId(targetRes)
+				linkList.Items = append(linkList.Items, NewLink(from, to, "-DOWN->", ref.path, ref.path+": "+ref.name))
+			}
+		}
+	}
+	return linkList
+}
+
+type objectReference struct {
+	kind      string
+	name      string
+	namespace string
+	path      string
+}
+
+func findObjectReferences(path string, key string, value interface{}) []objectReference {
+	var refs []objectReference
+
+	switch v := value.(type) {
+	case string:
+		if stem := strings.TrimSuffix(key, "Name"); stem != key {
+			if kind := referenceKind(stem); kind != "" && v != "" {
+				refs = append(refs, objectReference{kind: kind, name: v, path: path})
+			}
+		}
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok && name != "" && isReferenceKey(key) {
+			kind, _ := v["kind"].(string)
+			if kind == "" {
+				kind = referenceKind(trimReferenceSuffix(key))
+			}
+			if kind != "" {
+				namespace, _ := v["namespace"].(string)
+				refs = append(refs, objectReference{kind: kind, name: name, namespace: namespace, path: path + ".name"})
+				break
+			}
+		}
+		for _, k := range sortedKeys(v) {
+			refs = append(refs, findObjectReferences(path+"."+k, k, v[k])...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			refs = append(refs, findObjectReferences(fmt.Sprintf("%s[%d]", path, i), key, item)...)
+		}
+	}
+	return refs
+}
+
+func isReferenceKey(key string) bool {
+	return trimReferenceSuffix(key) != key
+}
+
+func trimReferenceSuffix(key string) string {
+	for _, suffix := range []string{"KeyRef", "Reference", "Ref"} {
+		if strings.HasSuffix(key, suffix) {
+			return strings.TrimSuffix(key, suffix)
+		}
+	}
+	return key
+}
+
+func referenceKind(stem string) string {
+	stem = strings.ToLower(stem)
+	for _, k := range commonReferenceKinds {
+		if strings.HasSuffix(stem, k.stem) {
+			return k.kind
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
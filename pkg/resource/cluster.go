@@ -0,0 +1,230 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClusterSourceFile is the Source.File of objects read from a cluster.
+const ClusterSourceFile = "cluster"
+
+// ListError is returned for a kind that could not be listed, e.g. because
+// the user may not list Secrets or the API server does not serve the kind.
+type ListError struct {
+	GroupVersionKind schema.GroupVersionKind
+	Err              error
+}
+
+func (e *ListError) Error() string {
+	return fmt.Sprintf("%s: %v", e.GroupVersionKind.Kind, e.Err)
+}
+
+func (e *ListError) Unwrap() error {
+	return e.Err
+}
+
+type clusterKind struct {
+	gvk schema.GroupVersionKind
+	// list returns the typed list. Typed clients leave the TypeMeta of the
+	// items empty, it is filled in from gvk afterwards.
+	list func(ctx context.Context, client kubernetes.Interface, namespace string) (runtime.Object, error)
+	// allNamespacesOnly is set for cluster RBAC, which would swamp the
+	// diagram of a single namespace. Other cluster-scoped kinds are few and
+	// referenced from namespaced objects, so they are always listed.
+	allNamespacesOnly bool
+	// fallback is set for an older version that is only listed when the
+	// server does not offer the kind through the entry before it, e.g.
+	// batch/v1beta1 CronJobs on servers older than Kubernetes 1.21. Its list
+	// converts the items to the newer type.
+	fallback bool
+}
+
+var clusterKinds = []clusterKind{
+	{gvk: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.BatchV1().CronJobs(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"}, fallback: true, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		list, err := c.BatchV1beta1().CronJobs(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return convertList(list, &batchv1.CronJobList{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.NetworkingV1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "IngressClass"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Service"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.AutoscalingV1().HorizontalPodAutoscalers(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.PolicyV1().PodDisruptionBudgets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}, fallback: true, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		list, err := c.PolicyV1beta1().PodDisruptionBudgets(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return convertList(list, &policyv1.PodDisruptionBudgetList{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolume"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "storage.k8s.io", Version: "v1", Kind: "StorageClass"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().ServiceAccounts(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.RbacV1().Roles(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, allNamespacesOnly: true, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.RbacV1().RoleBindings(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"}, allNamespacesOnly: true, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, list: func(ctx context.Context, c kubernetes.Interface, ns string) (runtime.Object, error) {
+		return c.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	}},
+}
+
+// NewAPIResourceListFromCluster lists the supported kinds through client.
+// An empty namespace lists all namespaces. Unlike NewAPIResourceList, no
+// children are synthesized: the ReplicaSets and Pods that actually run are
+// listed instead. Kinds that cannot be listed are reported as ListErrors.
+// Kinds with an older fallback version are listed through the newest version
+// the discovery API reports as served.
+func NewAPIResourceListFromCluster(ctx context.Context, client kubernetes.Interface, namespace string) (APIResourceList, []error) {
+	res := APIResourceList{defaultNamespace: namespace}
+	var errs []error
+
+	skipFallback := false
+	for i, kind := range clusterKinds {
+		if kind.fallback && skipFallback {
+			continue
+		}
+		if namespace != "" && kind.allNamespacesOnly {
+			continue
+		}
+		if i+1 < len(clusterKinds) && clusterKinds[i+1].fallback {
+			served, err := isServed(client.Discovery(), kind.gvk)
+			if err != nil {
+				errs = append(errs, &ListError{GroupVersionKind: kind.gvk, Err: err})
+				skipFallback = true
+				continue
+			}
+			skipFallback = served
+			if !served {
+				continue
+			}
+		}
+		listNamespace := namespace
+		if IsClusterScoped(kind.gvk.Kind) {
+			listNamespace = ""
+		}
+		list, err := kind.list(ctx, client, listNamespace)
+		if err == nil {
+			var items []runtime.Object
+			if items, err = meta.ExtractList(list); err == nil {
+				for _, item := range items {
+					item.GetObjectKind().SetGroupVersionKind(kind.gvk)
+					if secret, ok := item.(*corev1.Secret); ok {
+						// Only references to Secrets are drawn, their data
+						// is never needed.
+						secret.Data = nil
+						secret.StringData = nil
+					}
+					if r, ok := item.(APIResource); ok {
+						res.add(r, Source{File: ClusterSourceFile, Index: len(res.Items)})
+					}
+				}
+			}
+		}
+		if err != nil {
+			errs = append(errs, &ListError{GroupVersionKind: kind.gvk, Err: err})
+		}
+	}
+
+	return res, errs
+}
+
+// isServed asks the discovery API whether the server offers gvk.
+func isServed(client discovery.DiscoveryInterface, gvk schema.GroupVersionKind) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == gvk.Kind {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// convertList copies in into out, a list of a newer version with the same
+// fields, e.g. a batch/v1beta1 CronJobList into a batch/v1 one.
+func convertList(in runtime.Object, out runtime.Object) (runtime.Object, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,189 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func ownerReference(kind string, name string, uid types.UID) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid, Controller: &controller}}
+}
+
+// serveResources makes the fake discovery API offer CronJobs and
+// PodDisruptionBudgets through the given group versions only. The fake,
+// unlike a server, does not return NotFound for missing group versions, so
+// all of them are listed.
+func serveResources(client *fake.Clientset, batch string, policy string) {
+	kinds := map[string]metav1.APIResource{
+		batch:  {Name: "cronjobs", Namespaced: true, Kind: "CronJob"},
+		policy: {Name: "poddisruptionbudgets", Namespaced: true, Kind: "PodDisruptionBudget"},
+	}
+	client.Resources = nil
+	for _, groupVersion := range []string{"batch/v1", "batch/v1beta1", "policy/v1", "policy/v1beta1"} {
+		resources := &metav1.APIResourceList{GroupVersion: groupVersion}
+		if kind, ok := kinds[groupVersion]; ok {
+			resources.APIResources = append(resources.APIResources, kind)
+		}
+		client.Resources = append(client.Resources, resources)
+	}
+}
+
+func findItem(list APIResourceList, kind string, name string) APIResource {
+	for _, item := range list.Items {
+		if item.GroupVersionKind().Kind == kind && item.GetName() == name {
+			return item
+		}
+	}
+	return nil
+}
+
+func TestNewAPIResourceListFromCluster(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", UID: "d1"}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-5d4f", Namespace: "shop", UID: "r1",
+			OwnerReferences: ownerReference("Deployment", "web", "d1")}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-5d4f-x2k", Namespace: "shop", UID: "p1",
+			OwnerReferences: ownerReference("ReplicaSet", "web-5d4f", "r1")}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+			Data: map[string][]byte{"password": []byte("hunter2")}, StringData: map[string]string{"user": "admin"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "admin"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "kube-system"}},
+	)
+	serveResources(client, "batch/v1", "policy/v1")
+
+	list, errs := NewAPIResourceListFromCluster(context.Background(), client, "shop")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	deployment := findItem(list, "Deployment", "web")
+	replicaSet := findItem(list, "ReplicaSet", "web-5d4f")
+	pod := findItem(list, "Pod", "web-5d4f-x2k")
+	if deployment == nil || replicaSet == nil || pod == nil {
+		t.Fatalf("missing workload objects in %v", list.Items)
+	}
+	if !IsOwnedBy(replicaSet, deployment) {
+		t.Error("ReplicaSet is not owned by the Deployment")
+	}
+	if !IsOwnedBy(pod, replicaSet) {
+		t.Error("Pod is not owned by the ReplicaSet")
+	}
+	for _, item := range list.Items {
+		if list.Sources[item].Synthesized {
+			t.Errorf("%s %s was synthesized, cluster objects never are", item.GroupVersionKind().Kind, item.GetName())
+		}
+		if list.Sources[item].File != ClusterSourceFile {
+			t.Errorf("%s %s has source file %q", item.GroupVersionKind().Kind, item.GetName(), list.Sources[item].File)
+		}
+	}
+
+	secret, ok := findItem(list, "Secret", "db").(*corev1.Secret)
+	if !ok {
+		t.Fatal("missing Secret db")
+	}
+	if secret.Data != nil || secret.StringData != nil {
+		t.Errorf("Secret data was not stripped: %v %v", secret.Data, secret.StringData)
+	}
+
+	if findItem(list, "ClusterRole", "admin") != nil {
+		t.Error("ClusterRoles must only be listed for all namespaces")
+	}
+	if findItem(list, "Pod", "other") != nil {
+		t.Error("Pod of another namespace was listed")
+	}
+
+	all, errs := NewAPIResourceListFromCluster(context.Background(), client, "")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if findItem(all, "ClusterRole", "admin") == nil {
+		t.Error("ClusterRoles are missing for all namespaces")
+	}
+}
+
+func TestNewAPIResourceListFromClusterForbidden(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "shop"}},
+	)
+	serveResources(client, "batch/v1", "policy/v1")
+	client.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("not allowed"))
+	})
+
+	list, errs := NewAPIResourceListFromCluster(context.Background(), client, "shop")
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want one for Secrets", errs)
+	}
+	var listErr *ListError
+	if !errors.As(errs[0], &listErr) || listErr.GroupVersionKind.Kind != "Secret" {
+		t.Fatalf("got error %v, want a ListError for Secrets", errs[0])
+	}
+	if !apierrors.IsForbidden(listErr) {
+		t.Errorf("ListError does not wrap the Forbidden error: %v", listErr)
+	}
+	if findItem(list, "ConfigMap", "settings") == nil {
+		t.Error("other kinds must still be listed")
+	}
+}
+
+func TestNewAPIResourceListFromClusterVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   string
+		policy  string
+		version string
+	}{
+		{name: "Kubernetes 1.25", batch: "batch/v1", policy: "policy/v1", version: "v1"},
+		{name: "Kubernetes 1.20", batch: "batch/v1beta1", policy: "policy/v1beta1", version: "v1beta1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(
+				&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "shop"}},
+				&batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "shop"}},
+				&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}},
+				&policyv1beta1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}},
+			)
+			serveResources(client, tt.batch, tt.policy)
+
+			list, errs := NewAPIResourceListFromCluster(context.Background(), client, "shop")
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			cronJobs := 0
+			pdbs := 0
+			for _, item := range list.Items {
+				switch item.(type) {
+				case *batchv1.CronJob:
+					cronJobs++
+				case *policyv1.PodDisruptionBudget:
+					pdbs++
+				default:
+					continue
+				}
+				if version := item.GroupVersionKind().Version; version != tt.version {
+					t.Errorf("%s listed through %s, want %s", item.GroupVersionKind().Kind, version, tt.version)
+				}
+			}
+			if cronJobs != 1 || pdbs != 1 {
+				t.Errorf("got %d CronJobs and %d PodDisruptionBudgets, want one each", cronJobs, pdbs)
+			}
+		})
+	}
+}
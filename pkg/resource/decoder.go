@@ -0,0 +1,166 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+const jsonGuessBufferSize = 4096
+
+// DecodeDocuments splits a YAML or JSON stream into its documents. Documents
+// that contain nothing but blank lines and comments are skipped, so Index is
+// the position of the document among the non-empty ones in the stream. A
+// malformed JSON document ends a JSON stream: the documents before it are
+// returned along with a ParseError.
+func DecodeDocuments(path string, r io.Reader) ([]Document, error) {
+	reader, _, isJSON := utilyaml.GuessJSONStream(r, jsonGuessBufferSize)
+	if isJSON {
+		return decodeJSONDocuments(path, reader)
+	}
+	return decodeYAMLDocuments(path, reader)
+}
+
+func decodeJSONDocuments(path string, r io.Reader) ([]Document, error) {
+	var documents []Document
+
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			return documents, nil
+		}
+		source := Source{File: path, Index: len(documents)}
+		if err != nil {
+			return documents, &ParseError{Source: source, Err: err}
+		}
+		documents = append(documents, Document{Source: source, Data: raw})
+	}
+}
+
+func decodeYAMLDocuments(path string, r io.Reader) ([]Document, error) {
+	var documents []Document
+	var buffer bytes.Buffer
+
+	flush := func() {
+		if !isEmptyYAMLDocument(buffer.Bytes()) {
+			data := make([]byte, buffer.Len())
+			copy(data, buffer.Bytes())
+			source := Source{File: path, Index: len(documents)}
+			documents = append(documents, Document{Source: source, Data: data})
+		}
+		buffer.Reset()
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return documents, fmt.Errorf("%s: %v", path, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case isYAMLDocumentStart(line):
+			flush()
+			// Content after the marker (e.g. "--- # comment") belongs to the
+			// next document.
+			buffer.WriteString(strings.TrimPrefix(line, "---"))
+			buffer.WriteByte('\n')
+		case isYAMLDocumentEnd(line):
+			flush()
+		default:
+			buffer.WriteString(line)
+			buffer.WriteByte('\n')
+		}
+
+		if err == io.EOF {
+			flush()
+			return documents, nil
+		}
+	}
+}
+
+// unwrapLists replaces List documents, e.g. the output of "kubectl get -o
+// yaml" or a ConfigMapList, with one document per item. The items keep the
+// Source of the list.
+func unwrapLists(documents []Document) ([]Document, []error) {
+	var unwrapped []Document
+	var errs []error
+
+	for _, document := range documents {
+		items, err := listItems(document.Data)
+		if err != nil {
+			errs = append(errs, &ParseError{Source: document.Source, Err: err})
+			continue
+		}
+		if items == nil {
+			unwrapped = append(unwrapped, document)
+			continue
+		}
+		for _, item := range items {
+			unwrapped = append(unwrapped, Document{Source: document.Source, Data: item})
+		}
+	}
+	return unwrapped, errs
+}
+
+// listItems returns the items of a List document as JSON, or nil if data is
+// not a list. Items of typed lists may omit their kind and apiVersion, those
+// are taken from the list.
+func listItems(data []byte) ([][]byte, error) {
+	gvk, err := checkGroupVersionKind(data)
+	if err != nil || !strings.HasSuffix(gvk.Kind, "List") {
+		return nil, nil
+	}
+
+	list := struct {
+		Items *[]map[string]interface{} `json:"items"`
+	}{}
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if list.Items == nil {
+		return nil, nil
+	}
+
+	items := [][]byte{}
+	for _, item := range *list.Items {
+		if _, ok := item["kind"]; !ok && gvk.Kind != "List" {
+			item["kind"] = strings.TrimSuffix(gvk.Kind, "List")
+		}
+		if _, ok := item["apiVersion"]; !ok && gvk.Kind != "List" {
+			item["apiVersion"] = gvk.GroupVersion().String()
+		}
+		itemData, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, itemData)
+	}
+	return items, nil
+}
+
+func isYAMLDocumentStart(line string) bool {
+	return line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t")
+}
+
+func isYAMLDocumentEnd(line string) bool {
+	return strings.TrimRight(line, " \t") == "..."
+}
+
+func isEmptyYAMLDocument(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
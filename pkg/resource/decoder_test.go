@@ -0,0 +1,107 @@
+package resource
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "single document",
+			input: "kind: Pod\n",
+			want:  []string{"kind: Pod\n"},
+		},
+		{
+			name:  "separator on line one",
+			input: "---\nkind: Pod\n---\nkind: Service\n",
+			want:  []string{"kind: Pod\n", "kind: Service\n"},
+		},
+		{
+			name:  "comment after separator",
+			input: "kind: Pod\n--- # the service\nkind: Service\n",
+			want:  []string{"kind: Pod\n", " # the service\nkind: Service\n"},
+		},
+		{
+			name:  "empty and comment-only documents",
+			input: "---\n# nothing here\n---\n\n---\nkind: Pod\n---\n",
+			want:  []string{"kind: Pod\n"},
+		},
+		{
+			name:  "separator inside block scalar",
+			input: "kind: ConfigMap\ndata:\n  file: |\n    a\n    ---\n    b\n---\nkind: Pod\n",
+			want:  []string{"kind: ConfigMap\ndata:\n  file: |\n    a\n    ---\n    b\n", "kind: Pod\n"},
+		},
+		{
+			name:  "not a separator",
+			input: "kind: Pod\n---foo: bar\n",
+			want:  []string{"kind: Pod\n---foo: bar\n"},
+		},
+		{
+			name:  "document end marker",
+			input: "kind: Pod\n...\nkind: Service\n",
+			want:  []string{"kind: Pod\n", "kind: Service\n"},
+		},
+		{
+			name:  "CRLF line endings",
+			input: "kind: Pod\r\n---\r\nkind: Service\r\n",
+			want:  []string{"kind: Pod\n", "kind: Service\n"},
+		},
+		{
+			name:  "no trailing newline",
+			input: "kind: Pod\n---\nkind: Service",
+			want:  []string{"kind: Pod\n", "kind: Service\n"},
+		},
+		{
+			name:  "JSON stream",
+			input: "{\"kind\": \"Pod\"}\n{\"kind\": \"Service\"}",
+			want:  []string{`{"kind": "Pod"}`, `{"kind": "Service"}`},
+		},
+		{
+			name:    "malformed JSON document",
+			input:   "{\"kind\": \"Pod\"}\n{\"kind\": }\n{\"kind\": \"Service\"}",
+			want:    []string{`{"kind": "Pod"}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := DecodeDocuments("test.yaml", strings.NewReader(tt.input))
+			if tt.wantErr {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("got error %v, want a ParseError", err)
+				}
+				if parseErr.Source.Index != len(tt.want) {
+					t.Errorf("got ParseError for document %d, want %d", parseErr.Source.Index, len(tt.want))
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for i, document := range documents {
+				if document.Source != (Source{File: "test.yaml", Index: i}) {
+					t.Errorf("document %d has source %+v", i, document.Source)
+				}
+				// Blank lines around a document do not matter to the parser.
+				got = append(got, strings.TrimSpace(string(document.Data)))
+			}
+			var want []string
+			for _, document := range tt.want {
+				want = append(want, strings.TrimSpace(document))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got documents %q, want %q", got, want)
+			}
+		})
+	}
+}
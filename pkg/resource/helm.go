@@ -0,0 +1,158 @@
+package resource
+
+import (
+	"fmt"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/strvals"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChartReleaseName is the release name charts are rendered with, the same
+// one "helm template" uses by default.
+const ChartReleaseName = "release-name"
+
+// ChartOptions holds the values a chart is rendered with, as given by
+// --values and --set.
+type ChartOptions struct {
+	ValueFiles []string
+	Values     []string
+	Namespace  string
+}
+
+// IsChart reports whether dir is a Helm chart, i.e. contains a Chart.yaml.
+func IsChart(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, chartutil.ChartfileName))
+	return err == nil && !fi.IsDir()
+}
+
+// ReadChart renders the chart in dir offline, like "helm template", and
+// splits the output into documents. Source.File is the template a document
+// was rendered from, e.g. "mychart/templates/deployment.yaml", and
+// Source.Chart is dir. A template that renders to invalid YAML is reported
+// as a ParseError without dropping the other templates.
+func ReadChart(dir string, options ChartOptions) ([]Document, []error) {
+	chrt, err := loader.Load(dir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", dir, err)}
+	}
+
+	if err := checkDependencies(chrt); err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", dir, err)}
+	}
+	values, err := chartValues(options)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if err := chartutil.ProcessDependencies(chrt, values); err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", dir, err)}
+	}
+	releaseOptions := chartutil.ReleaseOptions{
+		Name:      ChartReleaseName,
+		Namespace: options.Namespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+	renderValues, err := chartutil.ToRenderValues(chrt, values, releaseOptions, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", dir, err)}
+	}
+	rendered, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", dir, err)}
+	}
+
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var documents []Document
+	var errs []error
+	for _, name := range names {
+		// Partials and NOTES.txt render to text that is not a manifest.
+		if !isManifestTemplate(name) {
+			continue
+		}
+		templateDocuments, err := DecodeDocuments(name, strings.NewReader(rendered[name]))
+		for _, document := range templateDocuments {
+			document.Source.Chart = dir
+			documents = append(documents, document)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return documents, errs
+}
+
+// checkDependencies fails like "helm template" does when a dependency
+// declared in Chart.yaml has not been fetched into charts/.
+func checkDependencies(chrt *chart.Chart) error {
+	var missing []string
+	for _, dependency := range chrt.Metadata.Dependencies {
+		found := false
+		for _, fetched := range chrt.Dependencies() {
+			if fetched.Name() == dependency.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, dependency.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("found in Chart.yaml, but missing in charts/ directory: %s (run \"helm dependency build\")", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// isManifestTemplate follows helm: every template except partials and
+// NOTES.txt is a manifest, whatever its extension.
+func isManifestTemplate(name string) bool {
+	return !strings.HasPrefix(path.Base(name), "_") && !strings.HasSuffix(name, "NOTES.txt")
+}
+
+// chartValues merges the values files in order and then applies --set, as
+// helm does.
+func chartValues(options ChartOptions) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, file := range options.ValueFiles {
+		fileValues, err := chartutil.ReadValuesFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		values = mergeValues(values, fileValues)
+	}
+	for _, value := range options.Values {
+		if err := strvals.ParseInto(value, values); err != nil {
+			return nil, fmt.Errorf("--set %s: %v", value, err)
+		}
+	}
+	return values, nil
+}
+
+func mergeValues(a map[string]interface{}, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if v, ok := v.(map[string]interface{}); ok {
+			if av, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergeValues(av, v)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
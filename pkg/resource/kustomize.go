@@ -0,0 +1,115 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// KustomizationFile returns the path of the kustomization file in dir, or ""
+// if dir is not a kustomization.
+func KustomizationFile(dir string) string {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		file := filepath.Join(dir, name)
+		if fi, err := os.Stat(file); err == nil && !fi.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// ReadKustomization builds the kustomization in dir, like "kustomize build",
+// and splits the output into documents. Only local bases are supported:
+// kuml never clones or downloads anything, so a kustomization referring to a
+// URL fails before it is built. Source.File is the kustomization file.
+func ReadKustomization(dir string) ([]Document, error) {
+	if err := checkLocalKustomization(dir, map[string]bool{}); err != nil {
+		return nil, err
+	}
+
+	file := KustomizationFile(dir)
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := kustomizer.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	out, err := resMap.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return DecodeDocuments(file, bytes.NewReader(out))
+}
+
+// checkLocalKustomization returns an error if the kustomization in dir, or
+// any kustomization it includes, refers to a path that does not exist
+// locally, e.g. a git repository or an http URL.
+func checkLocalKustomization(dir string, visited map[string]bool) error {
+	if visited[dir] {
+		return nil
+	}
+	visited[dir] = true
+
+	file := KustomizationFile(dir)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var kustomization types.Kustomization
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	kustomization.FixKustomizationPostUnmarshalling()
+
+	paths := append(kustomization.Resources, kustomization.Components...)
+	for _, patch := range kustomization.PatchesStrategicMerge {
+		// Inline patches span several lines, paths never do.
+		if !strings.Contains(string(patch), "\n") {
+			paths = append(paths, string(patch))
+		}
+	}
+	for _, patch := range kustomization.Patches {
+		if patch.Path != "" {
+			paths = append(paths, patch.Path)
+		}
+	}
+
+	for _, path := range paths {
+		local := filepath.Join(dir, path)
+		fi, err := os.Stat(local)
+		if err != nil && isRemoteReference(path) {
+			return fmt.Errorf("%s: remote base %q is not supported, only local bases are", file, path)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		if fi.IsDir() && KustomizationFile(local) != "" {
+			if err := checkLocalKustomization(local, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isRemoteReference reports whether path is one of the URL and git forms
+// kustomize would fetch, e.g. "https://...", "git@github.com:..." or
+// "github.com/org/repo//dir?ref=v1".
+func isRemoteReference(path string) bool {
+	if strings.Contains(path, "://") || strings.HasPrefix(path, "git@") || strings.Contains(path, "?ref=") {
+		return true
+	}
+	for _, host := range []string{"github.com/", "gitlab.com/", "bitbucket.org/"} {
+		if strings.HasPrefix(path, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,188 +1,594 @@
 package resource
 
 import (
-	"bytes"
-	"io/ioutil"
+	"errors"
+	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	batchv1 "k8s.io/api/batch/v1"
-	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	extenshionsv1beta1 "k8s.io/api/extensions/v1beta1"
-	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sigs.k8s.io/yaml"
 )
 
 type APIResource interface {
 	GetName() string
 	GetNamespace() string
+	SetNamespace(namespace string)
 	GetLabels() map[string]string
 	GroupVersionKind() schema.GroupVersionKind
 }
 
 type APIResourceList struct {
-	Items []APIResource
-}
-
-func NewAPIResourceList(yamlByteSlice [][]byte) APIResourceList {
-	var res APIResourceList
-	for _, yamlByte := range yamlByteSlice {
-		kind, _ := checkResourceKind(yamlByte)
-		switch kind {
-		case "CronJob":
-			r := batchv1beta1.CronJob{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-
-			pod := corev1.Pod{}
-			pod.Name = r.Name
-			pod.Spec = r.Spec.JobTemplate.Spec.Template.Spec
-			pod.Labels = r.Spec.JobTemplate.Spec.Template.Labels
-			res.Items = append(res.Items, &pod)
-		case "Deployment":
-			r := appsv1.Deployment{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
+	Items   []APIResource
+	Sources map[APIResource]Source
 
-			rs := appsv1.ReplicaSet{}
-			rs.Kind = "ReplicaSet"
-			rs.Name = r.Name
-			rs.Labels = r.Spec.Template.Labels
-			rs.Spec.Selector = r.Spec.Selector
-			res.Items = append(res.Items, &rs)
-
-			pod := corev1.Pod{}
-			pod.Kind = "Pod"
-			pod.Name = r.Name
-			pod.Spec = r.Spec.Template.Spec
-			pod.Labels = r.Spec.Template.Labels
-			res.Items = append(res.Items, &pod)
-		case "Job":
+	defaultNamespace string
+}
+
+type Source struct {
+	File  string
+	Index int
+	// Synthesized is set for objects derived from the template of the
+	// workload at File and Index, e.g. the Pod of a Deployment.
+	Synthesized bool
+	// Chart is the directory of the Helm chart File was rendered from.
+	Chart string
+}
+
+// StdinPath is the path argument that makes ReadYaml read from standard input.
+const StdinPath = "-"
+
+type Document struct {
+	Source Source
+	Data   []byte
+}
+
+type ParseError struct {
+	Source Source
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: document %d: %v", e.Source.File, e.Source.Index, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// clusterScopedKinds lists the kinds that never have a namespace, including
+// those of common custom resources. Every other kind is treated as
+// namespaced, unless it is added with RegisterClusterScopedKind.
+var clusterScopedKinds = map[string]bool{
+	"APIService":                     true,
+	"CertificateSigningRequest":      true,
+	"ClusterIssuer":                  true,
+	"ClusterPolicy":                  true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"ClusterSecretStore":             true,
+	"CSIDriver":                      true,
+	"CSINode":                        true,
+	"CustomResourceDefinition":       true,
+	"IngressClass":                   true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"Node":                           true,
+	"PersistentVolume":               true,
+	"PodSecurityPolicy":              true,
+	"PriorityClass":                  true,
+	"RuntimeClass":                   true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+	"VolumeAttachment":               true,
+}
+
+func IsClusterScoped(kind string) bool {
+	return clusterScopedKinds[kind]
+}
+
+// RegisterClusterScopedKind marks kind, typically that of a custom resource,
+// as cluster-scoped so that objects of it are not put into the default
+// namespace.
+func RegisterClusterScopedKind(kind string) {
+	clusterScopedKinds[kind] = true
+}
+
+// add appends r to the list. Namespaced objects without a namespace are put
+// into the default namespace, as kubectl would do when applying them.
+func (l *APIResourceList) add(r APIResource, source Source) {
+	if l.Sources == nil {
+		l.Sources = map[APIResource]Source{}
+	}
+	if IsClusterScoped(r.GroupVersionKind().Kind) {
+		r.SetNamespace("")
+	} else if r.GetNamespace() == "" {
+		r.SetNamespace(l.defaultNamespace)
+	}
+	stripClusterFields(r)
+	l.Items = append(l.Items, r)
+	l.Sources[r] = source
+}
+
+// stripClusterFields drops the fields the API server fills in, which kuml
+// never draws, so that a dump of a cluster looks like the manifests it was
+// applied from.
+func stripClusterFields(r APIResource) {
+	if accessor, err := meta.Accessor(r); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	if u, ok := r.(*unstructured.Unstructured); ok {
+		unstructured.RemoveNestedField(u.Object, "status")
+		return
+	}
+	if v := reflect.ValueOf(r); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		if status := v.Elem().FieldByName("Status"); status.IsValid() && status.CanSet() {
+			status.Set(reflect.Zero(status.Type()))
+		}
+	}
+}
+
+func NewAPIResourceList(documents []Document, defaultNamespace string) (APIResourceList, []error) {
+	res := APIResourceList{defaultNamespace: defaultNamespace}
+	documents, errs := unwrapLists(documents)
+	for _, document := range documents {
+		yamlByte := document.Data
+		gvk, err := checkGroupVersionKind(yamlByte)
+		if err != nil {
+			errs = append(errs, &ParseError{Source: document.Source, Err: err})
+			continue
+		}
+		if gvk.Kind == "" {
+			errs = append(errs, &ParseError{Source: document.Source, Err: errors.New("missing kind")})
+			continue
+		}
+
+		switch gvk.GroupKind() {
+		case schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+			r := batchv1.CronJob{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "apps", Kind: "Deployment"}, schema.GroupKind{Group: "extensions", Kind: "Deployment"}:
+			r := appsv1.Deployment{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}, schema.GroupKind{Group: "extensions", Kind: "DaemonSet"}:
+			r := appsv1.DaemonSet{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "batch", Kind: "Job"}:
 			r := batchv1.Job{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-
-			pod := corev1.Pod{}
-			pod.Kind = "Pod"
-			pod.Name = r.Name
-			pod.Spec = r.Spec.Template.Spec
-			pod.Labels = r.Spec.Template.Labels
-			res.Items = append(res.Items, &pod)
-		case "Pod":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "Pod"}:
 			r := corev1.Pod{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-		case "ReplicaSet":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}, schema.GroupKind{Group: "extensions", Kind: "ReplicaSet"}:
 			r := appsv1.ReplicaSet{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-
-			pod := corev1.Pod{}
-			pod.Kind = "Pod"
-			pod.Name = r.Name
-			pod.Spec = r.Spec.Template.Spec
-			pod.Labels = r.Spec.Template.Labels
-			res.Items = append(res.Items, &pod)
-		case "StatefulSet":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
 			r := appsv1.StatefulSet{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-
-			pod := corev1.Pod{}
-			pod.Kind = "Pod"
-			pod.Name = r.Name
-			pod.Spec = r.Spec.Template.Spec
-			pod.Labels = r.Spec.Template.Labels
-			res.Items = append(res.Items, &pod)
-		case "Ingress":
-			r := extenshionsv1beta1.Ingress{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-		case "Service":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}, schema.GroupKind{Group: "extensions", Kind: "Ingress"}:
+			switch gvk.GroupVersion() {
+			case networkingv1.SchemeGroupVersion:
+				r := networkingv1.Ingress{}
+				if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+					res.add(&r, document.Source)
+				}
+			case networkingv1beta1.SchemeGroupVersion:
+				r := networkingv1beta1.Ingress{}
+				if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+					res.add(&r, document.Source)
+				}
+			default:
+				r := extenshionsv1beta1.Ingress{}
+				if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+					res.add(&r, document.Source)
+				}
+			}
+		case schema.GroupKind{Group: "networking.k8s.io", Kind: "IngressClass"}:
+			r := networkingv1.IngressClass{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "Service"}:
 			r := corev1.Service{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-		case "ConfigMap":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "ConfigMap"}:
 			r := corev1.ConfigMap{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-		case "Secret":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "Secret"}:
 			r := corev1.Secret{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-		case "HorizontalPodAutoscaler":
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}:
 			r := autoscalingv1.HorizontalPodAutoscaler{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
-		case "PodDisruptionBudget":
-			r := policyv1beta1.PodDisruptionBudget{}
-			yaml.Unmarshal(yamlByte, &r)
-			res.Items = append(res.Items, &r)
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "policy", Kind: "PodDisruptionBudget"}:
+			r := policyv1.PodDisruptionBudget{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "PersistentVolumeClaim"}:
+			r := corev1.PersistentVolumeClaim{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "PersistentVolume"}:
+			r := corev1.PersistentVolume{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "storage.k8s.io", Kind: "StorageClass"}:
+			r := storagev1.StorageClass{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "ServiceAccount"}:
+			r := corev1.ServiceAccount{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "Role"}:
+			r := rbacv1.Role{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:
+			r := rbacv1.ClusterRole{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:
+			r := rbacv1.RoleBinding{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:
+			r := rbacv1.ClusterRoleBinding{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "networking.k8s.io", Kind: "NetworkPolicy"}, schema.GroupKind{Group: "extensions", Kind: "NetworkPolicy"}:
+			r := networkingv1.NetworkPolicy{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
+		case schema.GroupKind{Group: "", Kind: "Namespace"}:
+			r := corev1.Namespace{}
+			if err = yaml.Unmarshal(yamlByte, &r); err == nil {
+				res.add(&r, document.Source)
+			}
 		default:
+			// Kinds without a typed schema, e.g. custom resources or a
+			// knative Service, are kept as unstructured objects so that they
+			// still show up.
+			r := unstructured.Unstructured{}
+			var jsonByte []byte
+			if jsonByte, err = yaml.YAMLToJSON(yamlByte); err == nil {
+				if err = r.UnmarshalJSON(jsonByte); err == nil {
+					res.add(&r, document.Source)
+				}
+			}
+		}
+		if err != nil {
+			errs = append(errs, &ParseError{Source: document.Source, Err: err})
+		}
+	}
+	res.synthesizeChildren()
+
+	return res, errs
+}
+
+// synthesizeChildren adds the objects that the controllers would create from
+// the templates of the workloads, e.g. the ReplicaSet and Pod of a
+// Deployment. Workloads whose real children are part of the input, as in a
+// dump of a cluster, are left alone; those children are linked through their
+// ownerReferences instead.
+func (l *APIResourceList) synthesizeChildren() {
+	for _, parent := range l.Items {
+		// An object with ownerReferences was created by a controller, e.g. a
+		// ReplicaSet of a dump. Its real children are the truth, and a
+		// missing one, e.g. of an old revision, does not exist.
+		if HasOwnerReferences(parent) {
+			continue
+		}
+		source := l.Sources[parent]
+		source.Synthesized = true
+		name := SynthesizedName(parent)
+
+		switch r := parent.(type) {
+		case *batchv1.CronJob:
+			if l.hasDependents(parent, "Job") {
+				continue
+			}
+			job := batchv1.Job{}
+			job.Kind = "Job"
+			job.APIVersion = "batch/v1"
+			job.Name = name
+			job.Namespace = r.Namespace
+			job.Labels = r.Spec.JobTemplate.Labels
+			job.Spec = r.Spec.JobTemplate.Spec
+			l.add(&job, source)
+
+			pod := templatePod(name, r.Namespace, r.Spec.JobTemplate.Spec.Template)
+			l.add(&pod, source)
+		case *appsv1.Deployment:
+			if l.hasDependents(parent, "ReplicaSet") {
+				continue
+			}
+			rs := appsv1.ReplicaSet{}
+			rs.Kind = "ReplicaSet"
+			rs.APIVersion = "apps/v1"
+			rs.Name = name
+			rs.Namespace = r.Namespace
+			rs.Labels = r.Spec.Template.Labels
+			rs.Spec.Selector = r.Spec.Selector
+			l.add(&rs, source)
+
+			if !scaledToZero(r.Spec.Replicas) {
+				pod := templatePod(name, r.Namespace, r.Spec.Template)
+				l.add(&pod, source)
+			}
+		case *appsv1.DaemonSet:
+			if !l.hasDependents(parent, "Pod") {
+				pod := templatePod(name, r.Namespace, r.Spec.Template)
+				l.add(&pod, source)
+			}
+		case *batchv1.Job:
+			if !l.hasDependents(parent, "Pod") {
+				pod := templatePod(name, r.Namespace, r.Spec.Template)
+				l.add(&pod, source)
+			}
+		case *appsv1.ReplicaSet:
+			if !l.hasDependents(parent, "Pod") && !scaledToZero(r.Spec.Replicas) {
+				pod := templatePod(name, r.Namespace, r.Spec.Template)
+				l.add(&pod, source)
+			}
+		case *appsv1.StatefulSet:
+			if l.hasDependents(parent, "Pod") || scaledToZero(r.Spec.Replicas) {
+				continue
+			}
+			pod := templatePod(name, r.Namespace, r.Spec.Template)
+
+			// The StatefulSet controller creates one PVC per template and
+			// Pod, named <template>-<statefulset>-<ordinal>, and adds it to
+			// the Pod's volumes. Model the claim once, without the ordinal.
+			pod.Spec.Volumes = append([]corev1.Volume{}, pod.Spec.Volumes...)
+			for _, template := range r.Spec.VolumeClaimTemplates {
+				pvc := corev1.PersistentVolumeClaim{}
+				pvc.Kind = "PersistentVolumeClaim"
+				pvc.APIVersion = "v1"
+				pvc.Name = template.Name + "-" + r.Name
+				pvc.Namespace = r.Namespace
+				pvc.Labels = template.Labels
+				pvc.Spec = template.Spec
+				l.add(&pvc, source)
+
+				pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+					Name: template.Name,
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.Name},
+					},
+				})
+			}
+			l.add(&pod, source)
 		}
 	}
+}
+
+// SynthesizedName returns the name of the children synthesized from the
+// template of parent, e.g. "web (Deployment)" for both the ReplicaSet and
+// the Pod of Deployment web. Real names cannot contain spaces, so the
+// children never clash with real objects or those of another parent.
+func SynthesizedName(parent APIResource) string {
+	return fmt.Sprintf("%s (%s)", parent.GetName(), parent.GroupVersionKind().Kind)
+}
 
-	return res
+func scaledToZero(replicas *int32) bool {
+	return replicas != nil && *replicas == 0
 }
 
-func checkResourceKind(yamlByte []byte) (string, error) {
-	yamlMap := make(map[string]interface{})
-	err := yaml.Unmarshal(yamlByte, &yamlMap)
+func templatePod(name string, namespace string, template corev1.PodTemplateSpec) corev1.Pod {
+	pod := corev1.Pod{}
+	pod.Kind = "Pod"
+	pod.APIVersion = "v1"
+	pod.Name = name
+	pod.Namespace = namespace
+	pod.Spec = template.Spec
+	pod.Labels = template.Labels
+	return pod
+}
 
+// hasDependents reports whether an object of kind in the list names owner in
+// its ownerReferences.
+func (l *APIResourceList) hasDependents(owner APIResource, kind string) bool {
+	for _, r := range l.Items {
+		if r.GroupVersionKind().Kind == kind && IsOwnedBy(r, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOwnedBy reports whether r names owner in its ownerReferences. The UIDs
+// are only compared when both are known, as manifests rarely carry them.
+func IsOwnedBy(r APIResource, owner APIResource) bool {
+	accessor, err := meta.Accessor(r)
 	if err != nil {
-		return "", err
+		return false
 	}
-	if len(yamlMap) == 0 {
-		return "", nil
+	ownerAccessor, err := meta.Accessor(owner)
+	if err != nil {
+		return false
+	}
+	if owner.GetNamespace() != "" && owner.GetNamespace() != r.GetNamespace() {
+		return false
+	}
+	for _, ref := range accessor.GetOwnerReferences() {
+		if ref.Kind != owner.GroupVersionKind().Kind || ref.Name != owner.GetName() {
+			continue
+		}
+		if ref.UID != "" && ownerAccessor.GetUID() != "" && ref.UID != ownerAccessor.GetUID() {
+			continue
+		}
+		return true
 	}
+	return false
+}
+
+// HasOwnerReferences reports whether r is managed by another object.
+func HasOwnerReferences(r APIResource) bool {
+	accessor, err := meta.Accessor(r)
+	return err == nil && len(accessor.GetOwnerReferences()) > 0
+}
 
-	kind := yamlMap["kind"].(string)
+func checkGroupVersionKind(yamlByte []byte) (schema.GroupVersionKind, error) {
+	typeMeta := metav1.TypeMeta{}
+	err := yaml.Unmarshal(yamlByte, &typeMeta)
 
-	return kind, nil
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+
+	return typeMeta.GroupVersionKind(), nil
 }
 
-func IsDirectory(path string) bool {
+func IsDirectory(path string) (bool, error) {
 	fi, err := os.Stat(path)
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
 	mode := fi.Mode()
-	return mode.IsDir()
+	return mode.IsDir(), nil
 }
 
-func ReadYamlFile(path string) [][]byte {
-	buf, err := ioutil.ReadFile(path)
+func ReadYamlFile(path string) ([]Document, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	defer f.Close()
 
-	return bytes.Split(buf, []byte("\n---"))
+	return DecodeDocuments(path, f)
 }
 
-func ReadYaml(recursive bool, paths ...string) [][]byte {
-	var yamlByteSlice [][]byte
+func ReadYamlStdin() ([]Document, error) {
+	return DecodeDocuments(StdinPath, os.Stdin)
+}
+
+// ReadOptions controls how ReadYaml reads directories.
+type ReadOptions struct {
+	// Recursive reads the subdirectories of directories as well.
+	Recursive bool
+	// Chart is used to render directories that are Helm charts.
+	Chart ChartOptions
+	// NoKustomize reads kustomization directories file by file instead of
+	// building them.
+	NoKustomize bool
+}
+
+// ReadYaml reads every document from paths. A directory containing a
+// Chart.yaml is rendered as a Helm chart and one containing a
+// kustomization.yaml is built, instead of being read file by file.
+// A *ParseError in the returned errors concerns a single document and the
+// other documents of the file are still returned, except that a JSON stream
+// cannot be resynchronized, so the documents after a malformed one are lost.
+// Any other error means a path could not be read at all.
+func ReadYaml(options ReadOptions, paths ...string) ([]Document, []error) {
+	var yamlByteSlice []Document
+	var errs []error
 	for _, path := range paths {
-		if IsDirectory(path) {
+		if path == StdinPath {
+			documents, err := ReadYamlStdin()
+			yamlByteSlice = append(yamlByteSlice, documents...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		isDir, err := IsDirectory(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if isDir && IsChart(path) {
+			documents, chartErrs := ReadChart(path, options.Chart)
+			yamlByteSlice = append(yamlByteSlice, documents...)
+			errs = append(errs, chartErrs...)
+		} else if isDir && !options.NoKustomize && KustomizationFile(path) != "" {
+			documents, err := ReadKustomization(path)
+			yamlByteSlice = append(yamlByteSlice, documents...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		} else if isDir {
 			err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
-				if !info.IsDir() {
-					yamlByteSlice = append(yamlByteSlice, ReadYamlFile(p)...)
-				} else {
-					if recursive {
-						yamlByteSlice = append(yamlByteSlice, ReadYaml(recursive, p)...)
+				if err != nil {
+					return err
+				}
+				if p == path {
+					return nil
+				}
+				if info.IsDir() {
+					// Subdirectories go through ReadYaml again, so that charts
+					// and kustomizations among them are detected.
+					if options.Recursive {
+						documents, subErrs := ReadYaml(options, p)
+						yamlByteSlice = append(yamlByteSlice, documents...)
+						errs = append(errs, subErrs...)
 					}
+					return filepath.SkipDir
+				}
+				documents, err := ReadYamlFile(p)
+				yamlByteSlice = append(yamlByteSlice, documents...)
+				if err != nil {
+					errs = append(errs, err)
 				}
 				return nil
 			})
 			if err != nil {
-				log.Fatal(err)
+				errs = append(errs, err)
 			}
 		} else {
-			yamlByteSlice = append(yamlByteSlice, ReadYamlFile(path)...)
+			documents, err := ReadYamlFile(path)
+			yamlByteSlice = append(yamlByteSlice, documents...)
+			if err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
-	return yamlByteSlice
+	return yamlByteSlice, errs
 }
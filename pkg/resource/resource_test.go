@@ -0,0 +1,168 @@
+package resource
+
+import (
+	"io/ioutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadYamlRecursive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"top.yaml", filepath.Join("sub", "nested.yaml")} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte("kind: ConfigMap\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		recursive bool
+		want      int
+	}{
+		{recursive: false, want: 1},
+		{recursive: true, want: 2},
+	}
+	for _, tt := range tests {
+		documents, errs := ReadYaml(ReadOptions{Recursive: tt.recursive}, dir)
+		if len(errs) > 0 {
+			t.Fatalf("Recursive: %v: unexpected errors: %v", tt.recursive, errs)
+		}
+		if len(documents) != tt.want {
+			t.Errorf("Recursive: %v: got %d documents, want %d", tt.recursive, len(documents), tt.want)
+		}
+	}
+}
+
+func TestSynthesizeChildren(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name: "manifest",
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web}
+spec:
+  template:
+    metadata: {labels: {app: web}}
+`,
+			want: []string{"ReplicaSet/web (Deployment)", "Pod/web (Deployment)"},
+		},
+		{
+			name: "scaled to zero",
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web}
+spec:
+  replicas: 0
+`,
+			want: []string{"ReplicaSet/web (Deployment)"},
+		},
+		{
+			name: "dump with an old revision",
+			input: `apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata: {name: web, uid: d1}
+- apiVersion: apps/v1
+  kind: ReplicaSet
+  metadata:
+    name: web-new
+    uid: r1
+    ownerReferences: [{apiVersion: apps/v1, kind: Deployment, name: web, uid: d1}]
+  spec: {replicas: 1}
+- apiVersion: apps/v1
+  kind: ReplicaSet
+  metadata:
+    name: web-old
+    uid: r2
+    ownerReferences: [{apiVersion: apps/v1, kind: Deployment, name: web, uid: d1}]
+  spec: {replicas: 0}
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: web-new-x2k
+    ownerReferences: [{apiVersion: apps/v1, kind: ReplicaSet, name: web-new, uid: r1}]
+`,
+		},
+		{
+			name: "finished Job of a CronJob",
+			input: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: backup-1600000000
+  ownerReferences: [{apiVersion: batch/v1beta1, kind: CronJob, name: backup}]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := DecodeDocuments("test.yaml", strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			list, errs := NewAPIResourceList(documents, "default")
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			var got []string
+			for _, item := range list.Items {
+				if list.Sources[item].Synthesized {
+					got = append(got, item.GroupVersionKind().Kind+"/"+item.GetName())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got synthesized %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAPIResourceListGroupKind(t *testing.T) {
+	input := `apiVersion: serving.knative.dev/v1
+kind: Service
+metadata: {name: hello}
+---
+apiVersion: v1
+kind: Service
+metadata: {name: hello}
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata: {name: legacy}
+`
+	documents, err := DecodeDocuments("test.yaml", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, errs := NewAPIResourceList(documents, "default")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if _, ok := list.Items[0].(*unstructured.Unstructured); !ok {
+		t.Errorf("knative Service is %T, want unstructured", list.Items[0])
+	}
+	if _, ok := list.Items[1].(*corev1.Service); !ok {
+		t.Errorf("core Service is %T, want *v1.Service", list.Items[1])
+	}
+	if _, ok := list.Items[2].(*appsv1.Deployment); !ok {
+		t.Errorf("extensions/v1beta1 Deployment is %T, want *v1.Deployment", list.Items[2])
+	}
+}